@@ -1,15 +1,27 @@
 package bigcache
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/mikaelnousiainen/bigcache/queue"
 )
 
 const (
 	minimumEntriesInShard = 10 // Minimum number of entries in single shard
+	// Shard queue grown beyond its initial capacity is shrunk with Config.ShrinkEnabled once it uses less than
+	// this ratio of its capacity for this many consecutive pushes and pops
+	shrinkUsedRatio       = 0.25
+	shrinkAfterOperations = 1000
+	// Shard utilization above which life window is shortened when AdaptiveTTL is enabled
+	adaptiveTTLThreshold = 0.5
 )
 
 // BigCache is fast, concurrent, evicting cache created to keep big number of entries without impact on performance.
@@ -18,18 +30,52 @@ const (
 type BigCache struct {
 	shards     []*cacheShard
 	lifeWindow uint64
-	clock      clock
-	hash       Hasher
-	config     Config
-	shardMask  uint64
-	shardSize  int
+	grace      uint64
+	// Life window of entries promoted to the warm tier
+	warmLifeWindow uint64
+	clock          Clock
+	hash           Hasher
+	config         Config
+	shardMask      uint64
+	shardSize      int
+	// Number of bytes allocated for shard queue on start
+	initialShardSize int
+	// Max number of bytes in shard queue, zero means no limit
+	maxShardSize int
+	// Number of entries in all shards, updated atomically
+	count int64
+	// Whether the cache was empty when OnEmpty or OnFirstEntry was called last time, 1 on start
+	empty int32
+	// Whether background cleanup is paused, 1 when paused
+	cleanupPaused int32
+	// Whether Close was called, 1 when closed
+	closed int32
+	// Closed by Close to stop background goroutines
+	done chan struct{}
+	// Sketch of keys sampled by Get, nil when Config.SampleHotKeys is not set
+	hotKeys *hotKeys
+	// Loads in progress started by GetOrLoad
+	loads loadGroup
+	// Queue of writes to Config.Store, nil unless StoreMode is WriteBehind
+	writeBehind *writeBehind
 }
 
 type cacheShard struct {
-	hashmap     map[uint64]uint32
+	hashmap     map[uint64]uint64
 	entries     queue.BytesQueue
 	lock        sync.RWMutex
 	entryBuffer []byte
+	stats       Stats
+	bloom       *bloomFilter
+	// Access frequencies of keys, nil unless EvictionPolicy is TinyLFU
+	sketch *frequencySketch
+	// Unlock functions returned by lockForRead, created once so that reads do not allocate them
+	unlock  func()
+	rUnlock func()
+	// Number of bytes taken by deleted and overwritten entries still kept in the queue
+	deadBytes int
+	// Number of chained entries of every home hash with colliding keys
+	chains map[uint64]int
 }
 
 // NewBigCache initialize new instance of BigCache
@@ -37,32 +83,101 @@ func NewBigCache(config Config) (*BigCache, error) {
 	return newBigCache(config, &systemClock{})
 }
 
-func newBigCache(config Config, clock clock) (*BigCache, error) {
+// NewWithClock initialize new instance of BigCache which reads current time from provided clock
+func NewWithClock(config Config, clock Clock) (*BigCache, error) {
+	return newBigCache(config, clock)
+}
+
+func newBigCache(config Config, clock Clock) (*BigCache, error) {
 
-	if !isPowerOfTwo(config.Shards) {
-		return nil, fmt.Errorf("Shards number must be power of two")
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
 	if config.Hasher == nil {
 		config.Hasher = newDefaultHasher()
 	}
 
+	if config.Compressor == nil {
+		config.Compressor = FlateCompressor{}
+	} else if config.CompressAbove == 0 {
+		config.CompressAbove = defaultCompressAbove
+	}
+
+	if config.SnapshotCodec == nil {
+		config.SnapshotCodec = BinarySnapshotCodec{}
+	}
+
+	if config.SlidingExpiration && config.IdleTimeout == 0 {
+		config.IdleTimeout = config.LifeWindow
+	}
+
 	cache := &BigCache{
-		shards:     make([]*cacheShard, config.Shards),
-		lifeWindow: uint64(config.LifeWindow.Seconds()),
-		clock:      clock,
-		hash:       config.Hasher,
-		config:     config,
-		shardMask:  uint64(config.Shards - 1),
+		shards:         make([]*cacheShard, config.Shards),
+		lifeWindow:     uint64(config.LifeWindow.Seconds()),
+		grace:          uint64(config.EvictionGrace.Seconds()),
+		warmLifeWindow: uint64(config.WarmLifeWindow.Seconds()),
+		clock:          clock,
+		hash:           config.Hasher,
+		config:         config,
+		shardMask:      uint64(config.Shards - 1),
+		empty:          1,
+		done:           make(chan struct{}),
+	}
+
+	if config.Store != nil && config.StoreMode == WriteBehind {
+		cache.writeBehind = newWriteBehind(config.Store, config.WriteBehindQueueSize, config.OnStoreError)
+	}
+	if config.SampleHotKeys > 0 {
+		cache.hotKeys = newHotKeys(config.SampleHotKeys)
+	}
+	if config.IdleTimeout > 0 {
+		cache.lifeWindow = uint64(config.IdleTimeout.Seconds())
+	}
+	if config.WarmLifeWindow == 0 {
+		cache.warmLifeWindow = 2 * cache.lifeWindow
 	}
 
 	cache.shardSize = max(config.MaxEntriesInWindow/config.Shards, minimumEntriesInShard)
+	cache.maxShardSize = convertMBToBytes(config.HardMaxCacheSize) / config.Shards
+	cache.initialShardSize = cache.shardSize * config.MaxEntrySize
+	if cache.maxShardSize > 0 && cache.initialShardSize > cache.maxShardSize {
+		cache.initialShardSize = cache.maxShardSize
+	}
 	for i := 0; i < config.Shards; i++ {
 		cache.shards[i] = &cacheShard{
-			hashmap:     make(map[uint64]uint32, cache.shardSize),
-			entries:     *queue.NewBytesQueue(cache.shardSize*config.MaxEntrySize, config.Verbose),
+			hashmap:     make(map[uint64]uint64, cache.shardSize),
+			entries:     *queue.NewBytesQueue(cache.initialShardSize, cache.maxShardSize, config.Verbose),
 			entryBuffer: make([]byte, config.MaxEntrySize+headersSizeInBytes),
 		}
+		shardIndex, shard := i, cache.shards[i]
+		shard.unlock, shard.rUnlock = shard.lock.Unlock, shard.lock.RUnlock
+		if config.BloomFilterBits > 0 {
+			shard.bloom = newBloomFilter(config.BloomFilterBits)
+		}
+		if config.EvictionPolicy == TinyLFU {
+			shard.sketch = newFrequencySketch(cache.shardSize)
+		}
+		if config.ShrinkEnabled {
+			shard.entries.EnableShrinking(shrinkUsedRatio, shrinkAfterOperations)
+		}
+		shard.entries.OnAllocation(func(oldCapacity, newCapacity int, duration time.Duration) {
+			atomic.AddInt64(&shard.stats.Reallocations, 1)
+			if config.OnShardGrow != nil {
+				config.OnShardGrow(shardIndex, newCapacity)
+			}
+			if config.OnReallocation != nil {
+				config.OnReallocation(shardIndex, oldCapacity, newCapacity, duration)
+			}
+		})
+	}
+
+	if config.CleanWindow > 0 {
+		ticker := time.NewTicker(config.CleanWindow)
+		go func() {
+			defer ticker.Stop()
+			cache.cleanUpLoop(ticker.C)
+		}()
 	}
 
 	return cache, nil
@@ -72,73 +187,784 @@ func isPowerOfTwo(number int) bool {
 	return (number & (number - 1)) == 0
 }
 
-// Get reads entry for the key
+// Get reads entry for the key. Returned slice points into the shard memory, so it may be overwritten
+// by later operations on the shard and must not be modified. Use GetWithCopy to keep the value.
 func (c *BigCache) Get(key string) ([]byte, error) {
+	return c.get(key, false)
+}
+
+// GetWithCopy reads copy of the entry for the key, taken under the shard lock.
+// It costs an allocation per read, but the value stays valid and can be modified.
+func (c *BigCache) GetWithCopy(key string) ([]byte, error) {
+	return c.get(key, true)
+}
+
+func (c *BigCache) get(key string, copyValue bool) ([]byte, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	return c.getNormalized(key, copyValue)
+}
+
+// getNormalized reads entry for the key which was already normalized and checked
+func (c *BigCache) getNormalized(key string, copyValue bool) ([]byte, error) {
+	if c.hotKeys != nil {
+		c.hotKeys.sample(key)
+	}
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
+	if shard.bloom != nil && !shard.bloom.mayContain(hashedKey) {
+		atomic.AddInt64(&shard.stats.Misses, 1)
+		return nil, notFound(key)
+	}
+	unlock := c.lockForRead(shard)
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	if readFlagsFromEntry(wrappedEntry)&flagCompressed == 0 {
+		value := readEntry(wrappedEntry)
+		if copyValue {
+			value = copyBytes(value)
+		}
+		unlock()
+		return value, nil
+	}
+	value := copyStoredValue(wrappedEntry)
+	unlock()
+	return c.decompress(value)
+}
+
+// GetWithInfo reads entry for the key together with its metadata.
+// Entry is reported as stale when it outlived the life window but is still kept within the eviction grace period.
+// Remaining TTL and ExpiresSoon let callers refresh the entry before it expires, e.g. stale-while-revalidate.
+func (c *BigCache) GetWithInfo(key string) (EntryInfo, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return EntryInfo{}, err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	unlock := c.lockForRead(shard)
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		unlock()
+		return EntryInfo{}, err
+	}
+	info, value := c.copyEntryInfo(wrappedEntry)
+	unlock()
+	return c.withValue(info, value)
+}
+
+// GetAndTouch reads copy of the entry for the key and extends its life by moving its timestamp forward by extendBy.
+// Entry keeps its position in the queue, so it still cannot be evicted before entries set earlier.
+func (c *BigCache) GetAndTouch(key string, extendBy time.Duration) ([]byte, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	shard.lock.Lock()
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		shard.lock.Unlock()
+		return nil, err
+	}
+	value := copyStoredValue(wrappedEntry)
+	writeTimestampToEntry(wrappedEntry, readTimestampFromEntry(wrappedEntry)+uint64(extendBy.Seconds()))
+	shard.lock.Unlock()
+	return c.decompress(value)
+}
+
+// MGetWithDeadline reads copies of entries for the keys, skipping keys which are not found.
+// Keys are grouped by shard and every group is read under a single lock. Deadline is checked before every group,
+// when it passed the keys read so far are returned with false, otherwise all keys are read and true is returned.
+func (c *BigCache) MGetWithDeadline(keys []string, deadline time.Time) (map[string][]byte, bool) {
+	groups := c.groupByShard(keys)
+	values := make(map[string][]byte, len(keys))
+	for shardIndex, shard := range c.shards {
+		group, ok := groups[shardIndex]
+		if !ok {
+			continue
+		}
+		if !time.Now().Before(deadline) {
+			return values, false
+		}
+		c.readGroup(shard, group, values)
+	}
+	return values, true
+}
+
+// GetMulti reads copies of entries for the keys, skipping keys which are not found.
+// Keys are grouped by shard and every group is read under a single lock, so reading many keys
+// takes every shard lock at most once. Returned map is keyed by normalized keys.
+func (c *BigCache) GetMulti(keys []string) map[string][]byte {
+	values := make(map[string][]byte, len(keys))
+	for shardIndex, group := range c.groupByShard(keys) {
+		c.readGroup(c.shards[shardIndex], group, values)
+	}
+	return values
+}
+
+// groupByShard normalizes the keys and groups them by index of their shard, skipping keys rejected by checkKey
+func (c *BigCache) groupByShard(keys []string) map[int][]string {
+	groups := make(map[int][]string)
+	for _, key := range keys {
+		key = c.normalizeKey(key)
+		if c.checkKey(key) != nil {
+			continue
+		}
+		shardIndex := int(c.hash.Sum64(key) & c.shardMask)
+		groups[shardIndex] = append(groups[shardIndex], key)
+	}
+	return groups
+}
+
+// GetConsistent reads copies of entries for the keys, skipping keys which are not found.
+// Locks of all shards holding the keys are taken, in shard order, before any key is read, so the result
+// is a consistent view which no Set, Delete or other write interleaves with. Writes to these shards
+// wait until all keys are read, so the number of keys should be kept small.
+func (c *BigCache) GetConsistent(keys []string) (map[string][]byte, error) {
+	groups := make(map[int][]string)
+	for _, key := range keys {
+		key = c.normalizeKey(key)
+		if err := c.checkKey(key); err != nil {
+			return nil, err
+		}
+		shardIndex := int(c.hash.Sum64(key) & c.shardMask)
+		groups[shardIndex] = append(groups[shardIndex], key)
+	}
+
+	unlocks := make([]func(), 0, len(groups))
+	for shardIndex, shard := range c.shards {
+		if _, ok := groups[shardIndex]; ok {
+			unlocks = append(unlocks, c.lockForRead(shard))
+		}
+	}
+	stored := make(map[string]storedValue, len(keys))
+	for shardIndex, group := range groups {
+		for key, value := range c.readKeys(c.shards[shardIndex], group) {
+			stored[key] = value
+		}
+	}
+	for _, unlock := range unlocks {
+		unlock()
+	}
+
+	values := make(map[string][]byte, len(stored))
+	for key, value := range stored {
+		if decompressed, err := c.decompress(value); err == nil {
+			values[key] = decompressed
+		}
+	}
+	return values, nil
+}
+
+// readGroup reads copies of entries for the keys stored in the shard into values
+// Values are decompressed after the shard lock is released.
+func (c *BigCache) readGroup(shard *cacheShard, keys []string, values map[string][]byte) {
+	unlock := c.lockForRead(shard)
+	stored := c.readKeys(shard, keys)
+	unlock()
+
+	for key, value := range stored {
+		if decompressed, err := c.decompress(value); err == nil {
+			values[key] = decompressed
+		}
+	}
+}
+
+// readKeys returns copies of values as stored for the keys found in the shard, shard must be locked
+func (c *BigCache) readKeys(shard *cacheShard, keys []string) map[string]storedValue {
+	stored := make(map[string]storedValue, len(keys))
+	for _, key := range keys {
+		wrappedEntry, err := c.lookupEntry(shard, key, c.hash.Sum64(key))
+		if err != nil {
+			continue
+		}
+		stored[key] = copyStoredValue(wrappedEntry)
+	}
+	return stored
+}
+
+// lookupEntry finds entry for reading and records hit or miss in shard stats.
+// Entry that outlived its life window and eviction grace period is reported as expired even before it is evicted.
+// Negative entry is counted as a hit and reported with ErrNegativeCached.
+func (c *BigCache) lookupEntry(shard *cacheShard, key string, hashedKey uint64) ([]byte, error) {
+	if shard.sketch != nil {
+		shard.sketch.increment(hashedKey)
+	}
+	wrappedEntry, err := c.getWrappedEntry(shard, key, hashedKey)
+	if err == nil {
+		err = c.checkExpired(key, wrappedEntry)
+	}
+	if err != nil {
+		atomic.AddInt64(&shard.stats.Misses, 1)
+		return nil, err
+	}
+	atomic.AddInt64(&shard.stats.Hits, 1)
+	if isNegative(wrappedEntry) {
+		return nil, ErrNegativeCached
+	}
+	if c.countsAccesses() {
+		c.recordAccess(wrappedEntry)
+	}
+	if c.config.RefreshAhead > 0 && c.expiresAhead(wrappedEntry) {
+		c.refreshAhead(key)
+	}
+	if c.config.IdleTimeout > 0 {
+		writeTimestampToEntry(wrappedEntry, uint64(c.clock.Epoch()))
+	}
+	if c.config.EvictionPolicy == LRU {
+		return c.moveToBack(shard, key, wrappedEntry)
+	}
+	return wrappedEntry, nil
+}
+
+func (c *BigCache) countsAccesses() bool {
+	return c.config.TrackAccessCount || c.config.PromoteAfterAccesses > 0
+}
+
+// readsModifyEntries checks whether reads write to entry headers and need the shard write lock
+func (c *BigCache) readsModifyEntries() bool {
+	return c.countsAccesses() || c.config.IdleTimeout > 0 || c.config.EvictionPolicy != FIFO
+}
+
+// lockForRead takes the shard read lock, or the write lock when reads modify headers of entries.
+// Access counters and idle timestamps live in entry headers, so updating them on read means taking the write lock
+// and serializing reads within a shard. Keeping counters in a side map updated atomically would keep reads on the read lock,
+// but it costs a map entry with pointers per key, which is what the byte queue is designed to avoid.
+func (c *BigCache) lockForRead(shard *cacheShard) (unlock func()) {
+	if c.readsModifyEntries() {
+		shard.lock.Lock()
+		return shard.unlock
+	}
 	shard.lock.RLock()
-	defer shard.lock.RUnlock()
+	return shard.rUnlock
+}
+
+// recordAccess increments saturating access counter of the entry and promotes it to the warm tier
+// once it was accessed PromoteAfterAccesses times, if tiers are enabled. It must be called under the shard write lock.
+func (c *BigCache) recordAccess(wrappedEntry []byte) {
+	accesses := readAccessesFromEntry(wrappedEntry)
+	if accesses < math.MaxUint32 {
+		accesses++
+		writeAccessesToEntry(wrappedEntry, accesses)
+	}
+	if c.config.PromoteAfterAccesses > 0 && accesses >= uint32(c.config.PromoteAfterAccesses) {
+		writeFlagsToEntry(wrappedEntry, readFlagsFromEntry(wrappedEntry)|flagPromoted)
+	}
+}
 
+func (c *BigCache) getWrappedEntry(shard *cacheShard, key string, hashedKey uint64) ([]byte, error) {
 	itemIndex := shard.hashmap[hashedKey]
 
-	if itemIndex == 0 {
-		return nil, notFound(key)
+	var wrappedEntry []byte
+	if itemIndex != 0 {
+		var err error
+		wrappedEntry, err = shard.entries.Get(int(itemIndex))
+		if err != nil {
+			return nil, c.handleCorruption(key, err)
+		}
+	}
+	if wrappedEntry == nil || !c.keyMatches(wrappedEntry, key) {
+		chainedEntry, _, ok := c.findChained(shard, key, hashedKey)
+		if !ok {
+			if wrappedEntry != nil {
+				atomic.AddInt64(&shard.stats.Collisions, 1)
+				if c.config.Verbose {
+					log.Printf("Collision detected. Both %q and %q have the same hash %x", key, readKeyFromEntry(wrappedEntry), hashedKey)
+				}
+			}
+			return nil, notFound(key)
+		}
+		wrappedEntry = chainedEntry
+	}
+	if c.config.VerifyChecksums && !verifyChecksumOfEntry(wrappedEntry) {
+		return nil, c.handleCorruption(key, errors.New("Entry does not match its checksum"))
+	}
+	return wrappedEntry, nil
+}
+
+// handleCorruption applies Config.OnCorruption to corruption of the entry detected with given cause
+func (c *BigCache) handleCorruption(key string, cause error) error {
+	err := corrupted(key)
+	switch c.config.OnCorruption {
+	case Panic:
+		panic(err)
+	case Callback:
+		if c.config.CorruptionHandler != nil {
+			c.config.CorruptionHandler(key, err)
+		}
+	default:
+		log.Printf("Corruption detected. Entry %q: %v", key, cause)
 	}
+	return err
+}
 
-	wrappedEntry, err := shard.entries.Get(int(itemIndex))
+// Set saves entry under the key. With Config.Store the entry is written to the store first,
+// an error of the store in WriteThrough mode is returned and the entry is not cached.
+func (c *BigCache) Set(key string, entry []byte) error {
+	return c.setWithMetadata(key, entry, nil, 0)
+}
+
+// SetMulti saves all entries, taking the lock of every shard at most once. Entries of a shard are set
+// under a single lock, so readers of the shard wait until all of them are set. When some entries cannot be set,
+// the others are still set and the first error is returned.
+func (c *BigCache) SetMulti(entries map[string][]byte) error {
+	groups, firstErr := c.groupEntriesByShard(entries)
+	defer c.notifyTransitions()
+	for shardIndex, group := range groups {
+		if err := c.setGroup(c.shards[shardIndex], group); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// groupEntriesByShard prepares entries for Set and groups them by index of their shard.
+// Entries rejected by prepareSet are skipped and the first error is returned.
+func (c *BigCache) groupEntriesByShard(entries map[string][]byte) (map[int]map[string][]byte, error) {
+	var firstErr error
+	groups := make(map[int]map[string][]byte)
+	for key, entry := range entries {
+		key, entry, err := c.prepareSet(key, entry)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		shardIndex := int(c.hash.Sum64(key) & c.shardMask)
+		if groups[shardIndex] == nil {
+			groups[shardIndex] = make(map[string][]byte)
+		}
+		groups[shardIndex][key] = entry
+	}
+	return groups, firstErr
+}
+
+// setGroup writes entries of the shard to Config.Store and saves those accepted by the store under a single lock,
+// returning the first error. The store is written and entries are compressed before the shard is locked,
+// so readers do not wait for either.
+func (c *BigCache) setGroup(shard *cacheShard, entries map[string][]byte) error {
+	var firstErr error
+	for key, entry := range entries {
+		if err := c.putToStore(key, entry); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			delete(entries, key)
+		}
+	}
+
+	values := make(map[string]storedValue, len(entries))
+	for key, entry := range entries {
+		values[key] = c.compress(entry)
+	}
+
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	for key, value := range values {
+		if err := c.set(shard, c.hash.Sum64(key), key, nil, value, 0); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (c *BigCache) setWithMetadata(key string, entry []byte, metadata []byte, priority uint8) error {
+	key, entry, err := c.prepareSet(key, entry)
 	if err != nil {
-		return nil, err
+		return err
+	}
+	if err := c.putToStore(key, entry); err != nil {
+		return err
 	}
-	if entryKey := readKeyFromEntry(wrappedEntry); key != entryKey {
-		if c.config.Verbose {
-			log.Printf("Collision detected. Both %q and %q have the same hash %x", key, entryKey, hashedKey)
+	value := c.compress(entry)
+
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	return c.set(shard, hashedKey, key, metadata, value, priority)
+}
+
+// prepareSet normalizes and checks the key and applies Config.BeforeSet to the entry
+func (c *BigCache) prepareSet(key string, entry []byte) (string, []byte, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return "", nil, err
+	}
+	entry, err := c.beforeSet(key, entry)
+	if err != nil {
+		return "", nil, err
+	}
+	return key, entry, nil
+}
+
+// beforeSet applies Config.BeforeSet to entry for the key which was already normalized
+func (c *BigCache) beforeSet(key string, entry []byte) ([]byte, error) {
+	if c.config.BeforeSet == nil {
+		return entry, nil
+	}
+	return c.config.BeforeSet(key, entry)
+}
+
+// WouldFit returns number of bytes the entry would take in its shard, including headers, and whether Set
+// would accept it considering HardMaxCacheSize, MaxEntryShardShare and MaxEntrySizeHard. Nothing is stored. The value is compressed
+// when it qualifies for compression, so the size matches what Set stores, but BeforeSet is not applied.
+func (c *BigCache) WouldFit(key string, value []byte) (wrappedSize int, fits bool) {
+	key = c.normalizeKey(key)
+	stored := c.compress(value)
+	storedKey, _ := c.storedKey(key)
+	wrappedSize = headersSizeInBytes + len(storedKey) + len(stored.data)
+	return wrappedSize, c.checkKey(key) == nil && c.checkEntrySize(wrappedSize) == nil
+}
+
+// Replace saves entry under the key only if the key already exists and its entry is not expired.
+// Entry kept within the eviction grace period can still be read, so it is replaced as well.
+// Returns whether the entry was replaced.
+func (c *BigCache) Replace(key string, entry []byte) (bool, error) {
+	key, entry, err := c.prepareSet(key, entry)
+	if err != nil {
+		return false, err
+	}
+	value := c.compress(entry)
+
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	if _, found := c.liveEntry(shard, key, hashedKey); !found {
+		return false, nil
+	}
+	if err := c.set(shard, hashedKey, key, nil, value, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Update atomically replaces the entry for the key with the value returned by fn.
+// fn receives a copy of the current value and whether the key was found; expired and negative entries are reported as not found.
+// Returned value is stored when keep is true, otherwise the entry is deleted.
+// fn runs under the shard write lock, so it must be fast and must not call back into the cache.
+// The value depends on the current entry, so it is also compressed and decompressed under the lock.
+// Returned value is checked against size limits like on Set, e.g. an entry bigger than Config.MaxEntrySizeHard
+// is rejected with error matching ErrEntryTooLarge and the current entry is kept.
+func (c *BigCache) Update(key string, fn func(old []byte, found bool) (new []byte, keep bool)) error {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	var old, metadata []byte
+	var priority uint8
+	wrappedEntry, err := c.getWrappedEntry(shard, key, hashedKey)
+	if err == nil {
+		err = c.checkExpired(key, wrappedEntry)
+	}
+	found := err == nil && !isNegative(wrappedEntry)
+	if found {
+		value, err := c.readValue(wrappedEntry)
+		if err != nil {
+			return err
 		}
-		return nil, notFound(key)
+		old = copyBytes(value)
+		metadata = copyBytes(readMetadataFromEntry(wrappedEntry))
+		priority = readPriorityFromEntry(wrappedEntry)
+	}
+
+	value, keep := fn(old, found)
+	if !keep {
+		if found {
+			c.notifyRemoval(wrappedEntry, Deleted)
+			c.deleteIndex(shard, wrappedEntry)
+			shard.markDead(wrappedEntry)
+		}
+		return nil
+	}
+	return c.set(shard, hashedKey, key, metadata, c.compress(value), priority)
+}
+
+// Append appends data to the entry for the key under the shard lock, creating the entry when missing.
+// The whole entry is rewritten at the end of the shard queue, so appending costs a copy of the value.
+// Metadata and priority of the entry are kept.
+func (c *BigCache) Append(key string, data []byte) error {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return err
 	}
-	return readEntry(wrappedEntry), nil
+	return c.appendToEntry(key, data, 0)
 }
 
-// Set saves entry under the key
-func (c *BigCache) Set(key string, entry []byte) {
+// AppendBounded appends data to the entry for the key, creating it when missing.
+// When the resulting value is longer than maxLen the oldest bytes are dropped from the front,
+// so the entry behaves like a bounded log buffer.
+func (c *BigCache) AppendBounded(key string, data []byte, maxLen int) error {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+	if maxLen <= 0 {
+		return fmt.Errorf("Max length must be greater than zero")
+	}
+	return c.appendToEntry(key, data, maxLen)
+}
+
+// appendToEntry appends data to the entry for the key, keeping at most maxLen last bytes when maxLen is positive
+func (c *BigCache) appendToEntry(key string, data []byte, maxLen int) error {
 	hashedKey := c.hash.Sum64(key)
 	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
 	shard.lock.Lock()
 	defer shard.lock.Unlock()
 
-	currentTimestamp := uint64(c.clock.epoch())
+	var value, metadata []byte
+	var priority uint8
+	if wrappedEntry, err := c.getWrappedEntry(shard, key, hashedKey); err == nil {
+		previousValue, err := c.readValue(wrappedEntry)
+		if err != nil {
+			return err
+		}
+		value = make([]byte, 0, len(previousValue)+len(data))
+		value = append(value, previousValue...)
+		metadata = copyBytes(readMetadataFromEntry(wrappedEntry))
+		priority = readPriorityFromEntry(wrappedEntry)
+	}
+	value = append(value, data...)
+	if maxLen > 0 && len(value) > maxLen {
+		value = value[len(value)-maxLen:]
+	}
+
+	return c.set(shard, hashedKey, key, metadata, c.compress(value), priority)
+}
+
+// set stores value prepared by compress, shard must be locked
+func (c *BigCache) set(shard *cacheShard, hashedKey uint64, key string, metadata []byte, value storedValue, priority uint8) error {
+	return c.setWithTimestamp(shard, hashedKey, key, metadata, value, priority, 0, 0, uint64(c.clock.Epoch()))
+}
+
+// setWithTimestamp stores the value with given header fields, zero ttl means the entry uses life window of the cache.
+// Value is compressed by the caller before the shard is locked.
+func (c *BigCache) setWithTimestamp(shard *cacheShard, hashedKey uint64, key string, metadata []byte, value storedValue,
+	priority uint8, ttl uint32, entryFlags byte, entryTimestamp uint64) error {
+	entry := value.data
+	flags := value.flags | entryFlags
+	storedKey, keyFlags := c.storedKey(key)
+	flags |= keyFlags
+	wrappedSize := headersSizeInBytes + len(storedKey) + len(metadata) + len(entry)
+	if err := c.checkEntrySize(wrappedSize); err != nil {
+		atomic.AddInt64(&shard.stats.RejectedSets, 1)
+		return err
+	}
+
+	probe, previousIndex, err := c.findSlot(shard, key, hashedKey, c.config.OnChainFull == EvictOldestInChain)
+	if err != nil {
+		atomic.AddInt64(&shard.stats.RejectedSets, 1)
+		return err
+	}
+	slot := probeSlot(hashedKey, probe)
+	flags |= byte(probe) << probeShift
+
+	var version uint32 = 1
+	wrap := func() []byte {
+		w := wrapEntryWithMetadata(entryTimestamp, slot, storedKey, metadata, entry, &shard.entryBuffer)
+		writeFlagsToEntry(w, flags)
+		writePriorityToEntry(w, priority)
+		writeVersionToEntry(w, version)
+		writeTTLToEntry(w, ttl)
+		if c.config.VerifyChecksums {
+			writeChecksumToEntry(w)
+		}
+		return w
+	}
+	recordCompression := func() {
+		if value.compressed() {
+			atomic.AddInt64(&shard.stats.CompressedEntries, 1)
+			atomic.AddInt64(&shard.stats.RawBytes, int64(value.rawLength))
+			atomic.AddInt64(&shard.stats.CompressedBytes, int64(len(entry)))
+		}
+	}
+
+	currentTimestamp := uint64(c.clock.Epoch())
 
-	if previousIndex := shard.hashmap[hashedKey]; previousIndex != 0 {
+	if previousIndex != 0 {
 		if previousEntry, err := shard.entries.Get(int(previousIndex)); err == nil {
-			resetKeyFromEntry(previousEntry)
+			version = readVersionFromEntry(previousEntry) + 1
+			c.notifyRemoval(previousEntry, Replaced)
+			if c.overwritesInPlace() && len(previousEntry) == wrappedSize {
+				copy(previousEntry, wrap())
+				atomic.AddInt64(&shard.stats.InPlaceOverwrites, 1)
+				recordCompression()
+				return nil
+			}
+			shard.markDead(previousEntry)
 		}
 	}
 
 	if oldestEntry, err := shard.entries.Peek(); err == nil {
-		c.onEvict(oldestEntry, currentTimestamp, func() {
-			shard.entries.Pop()
-			hash := readHashFromEntry(oldestEntry)
-			delete(shard.hashmap, hash)
+		c.onEvict(shard, oldestEntry, currentTimestamp, func() {
+			c.removeOldestEntry(shard, Expired)
 		})
 	}
 
-	w := wrapEntry(currentTimestamp, hashedKey, key, entry, &shard.entryBuffer)
-	index := shard.entries.Push(w)
-	shard.hashmap[hashedKey] = uint32(index)
+	if shard.sketch != nil {
+		shard.sketch.increment(hashedKey)
+		if previousIndex == 0 && !c.admit(shard, hashedKey, headersSizeInBytes+len(storedKey)+len(metadata)+len(entry)) {
+			return ErrEntryNotAdmitted
+		}
+	}
+
+	if c.config.MaxEntriesPerShard > 0 && previousIndex == 0 {
+		for len(shard.hashmap) >= c.config.MaxEntriesPerShard && c.evictLowestPriority(shard) {
+		}
+	}
+
+	if err := c.push(shard, slot, wrap()); err != nil {
+		return err
+	}
+	recordCompression()
+	c.compactIfNeeded(shard)
+	return nil
 }
 
-// Clear deletes all entries in all shards
+// overwritesInPlace checks whether entries of the same size are overwritten where they are stored
+func (c *BigCache) overwritesInPlace() bool {
+	return c.config.OverwriteInPlace && c.config.EvictionPolicy != LRU
+}
+
+// push appends wrapped entry to the shard queue, evicting the oldest entries when the queue is full,
+// and points hashed key at it
+func (c *BigCache) push(shard *cacheShard, hashedKey uint64, w []byte) error {
+	for {
+		index, err := shard.entries.Push(w)
+		if err == nil {
+			if _, ok := shard.hashmap[hashedKey]; !ok {
+				c.chain(shard, w)
+			}
+			c.putIndex(shard, hashedKey, uint64(index))
+			if shard.bloom != nil {
+				shard.bloom.add(homeHash(w))
+			}
+			return nil
+		}
+		if !c.evictForSpace(shard) {
+			c.deleteIndex(shard, w)
+			return ErrShardFull
+		}
+	}
+}
+
+// Delete removes entry for the key. With Config.Store the key is deleted from the store first,
+// an error of the store in WriteThrough mode is returned and the entry stays in the cache.
+func (c *BigCache) Delete(key string) error {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+	if err := c.deleteFromStore(key); err != nil {
+		return err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	wrappedEntry, err := c.getWrappedEntry(shard, key, hashedKey)
+	if err != nil {
+		atomic.AddInt64(&shard.stats.DelMisses, 1)
+		return err
+	}
+	atomic.AddInt64(&shard.stats.DelHits, 1)
+	c.notifyRemoval(wrappedEntry, Deleted)
+	c.deleteIndex(shard, wrappedEntry)
+	shard.markDead(wrappedEntry)
+	c.compactIfNeeded(shard)
+	return nil
+}
+
+// Has checks whether entry for the key exists
+func (c *BigCache) Has(key string) bool {
+	key = c.normalizeKey(key)
+	if c.checkKey(key) != nil {
+		return false
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	if shard.bloom != nil && !shard.bloom.mayContain(hashedKey) {
+		return false
+	}
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+
+	_, err := c.getWrappedEntry(shard, key, hashedKey)
+	return err == nil
+}
+
+// Clear deletes all entries in all shards. Memory allocated for shards is kept for new entries.
 func (c *BigCache) Clear() {
+	c.clear(false)
+}
+
+// ResetAndRelease deletes all entries like Clear and replaces shard queues which grew beyond their initial capacity
+// with new ones of the initial capacity, so memory allocated during a burst of entries can be garbage collected.
+func (c *BigCache) ResetAndRelease() {
+	c.clear(true)
+}
+
+func (c *BigCache) clear(release bool) {
 	for _, shard := range c.shards {
 		shard.lock.Lock()
-		shard.entries.Clear()
-		shard.hashmap = make(map[uint64]uint32, c.shardSize)
+		if release && shard.entries.Capacity() > c.initialShardSize {
+			shard.entries.Reset(c.initialShardSize)
+		} else {
+			shard.entries.Clear()
+		}
+		shard.deadBytes = 0
+		shard.chains = nil
+		atomic.AddInt64(&c.count, -int64(len(shard.hashmap)))
+		shard.hashmap = make(map[uint64]uint64, c.shardSize)
+		if shard.bloom != nil {
+			shard.bloom.reset()
+		}
 		shard.lock.Unlock()
 	}
+	if c.hotKeys != nil {
+		c.hotKeys.reset()
+	}
+	c.notifyTransitions()
+}
+
+// ClearAsync deletes all entries in all shards on a background goroutine and closes returned channel when done.
+// Shards are cleared one at a time, so operations on other shards are not blocked meanwhile.
+func (c *BigCache) ClearAsync() <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		c.Clear()
+		close(done)
+	}()
+	return done
 }
 
 // Iterate calls the accept function for all key-value pairs in all shards.
 // Note that the implementation is not thread-safe
-func (c *BigCache) Iterate(accept func (string, []byte)) {
+func (c *BigCache) Iterate(accept func(string, []byte)) {
 	for _, shard := range c.shards {
 		for hashedKey, _ := range shard.hashmap {
 			key, value, err := c.getKeyAndValue(shard, hashedKey)
@@ -151,13 +977,465 @@ func (c *BigCache) Iterate(accept func (string, []byte)) {
 	}
 }
 
-func (c* BigCache) Size() uint64 {
-	var count uint64
+// IterateShard calls the accept function for all key-value pairs in the shard with given index.
+// Entries are copied under the shard read lock and accept is called after the lock is released,
+// so it is safe to use the cache from within accept. Values passed to accept are copies.
+func (c *BigCache) IterateShard(index int, accept func(key string, value []byte)) error {
+	if err := c.checkShardIndex(index); err != nil {
+		return err
+	}
+
+	for _, entry := range c.copyEntries(c.shards[index], nil) {
+		accept(entry.Key, entry.Value)
+	}
+	return nil
+}
+
+// IterateWithInfo calls the accept function for copies of all entries together with their metadata,
+// e.g. insertion timestamp and remaining TTL to drive refresh-ahead or to build age histograms.
+// Entries of a shard are copied under its read lock and accept is called after the lock is released,
+// so it is safe to use the cache from within accept.
+func (c *BigCache) IterateWithInfo(accept func(info EntryInfo)) {
 	for _, shard := range c.shards {
-		count += uint64(len(shard.hashmap))
+		for _, entry := range c.copyEntries(shard, nil) {
+			accept(entry)
+		}
 	}
+}
 
-	return count
+// IterateKeys calls the accept function for keys of all entries, stopping when it returns false.
+// Only keys are read, values are neither decoded nor copied. Keys of a shard are collected under its
+// read lock and accept is called after the lock is released, so it is safe to use the cache from within accept.
+func (c *BigCache) IterateKeys(accept func(key string) bool) {
+	var keys []string
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		keys = keys[:0]
+		for _, index := range shard.hashmap {
+			if wrappedEntry, err := shard.entries.Get(int(index)); err == nil {
+				keys = append(keys, readKeyFromEntry(wrappedEntry))
+			}
+		}
+		shard.lock.RUnlock()
+
+		for _, key := range keys {
+			if !accept(key) {
+				return
+			}
+		}
+	}
+}
+
+// IterateLazy calls the accept function for all keys in all shards together with an accessor of the value.
+// Keys of a shard are collected under its read lock and accept is called after the lock is released.
+// Value is copied only when the accessor is called, which makes filtering by key cheap.
+// The accessor must be called before accept returns, later calls return nil. It also returns nil
+// when the entry was removed or overwritten since its key was collected.
+func (c *BigCache) IterateLazy(accept func(key string, value func() []byte)) {
+	type location struct {
+		key       string
+		hashedKey uint64
+		index     uint64
+	}
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		locations := make([]location, 0, len(shard.hashmap))
+		for hashedKey, index := range shard.hashmap {
+			if wrappedEntry, err := shard.entries.Get(int(index)); err == nil {
+				locations = append(locations, location{readKeyFromEntry(wrappedEntry), hashedKey, index})
+			}
+		}
+		shard.lock.RUnlock()
+
+		for _, l := range locations {
+			valid := true
+			accept(l.key, func() []byte {
+				if !valid {
+					return nil
+				}
+				return c.copyValueAt(shard, l.hashedKey, l.index)
+			})
+			valid = false
+		}
+	}
+}
+
+// copyValueAt returns copy of the value stored at index if hashed key still points at it
+func (c *BigCache) copyValueAt(shard *cacheShard, hashedKey uint64, index uint64) []byte {
+	shard.lock.RLock()
+	if shard.hashmap[hashedKey] != index {
+		shard.lock.RUnlock()
+		return nil
+	}
+	wrappedEntry, err := shard.entries.Get(int(index))
+	if err != nil {
+		shard.lock.RUnlock()
+		return nil
+	}
+	stored := copyStoredValue(wrappedEntry)
+	shard.lock.RUnlock()
+
+	value, err := c.decompress(stored)
+	if err != nil {
+		return nil
+	}
+	return value
+}
+
+// SortedIterate calls the accept function for all key-value pairs, visiting shards in index order
+// and keys of every shard in sorted order, so an unchanged cache is always visited in the same order.
+// Entries of a shard are copied under its read lock and sorted after it is released, which costs
+// O(n log n), so it is meant for tooling and tests, not for the hot path. Values passed to accept are copies.
+func (c *BigCache) SortedIterate(accept func(string, []byte)) {
+	for _, shard := range c.shards {
+		entries := c.copyEntries(shard, nil)
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Key < entries[j].Key
+		})
+		for _, entry := range entries {
+			accept(entry.Key, entry.Value)
+		}
+	}
+}
+
+// DumpByAge returns copies of all entries sorted by insertion timestamp, oldest first
+// unless newestFirst is set. Shards are read one by one under their read locks.
+// It copies every value and sorts all entries in O(n log n), so it is meant for
+// diagnostics and exports, not for the hot path.
+func (c *BigCache) DumpByAge(newestFirst bool) []EntryInfo {
+	var entries []EntryInfo
+	for _, shard := range c.shards {
+		entries = append(entries, c.copyEntries(shard, nil)...)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if newestFirst {
+			return entries[i].Timestamp > entries[j].Timestamp
+		}
+		return entries[i].Timestamp < entries[j].Timestamp
+	})
+	return entries
+}
+
+// GetHashRange returns copies of all entries which key hash is within [lo, hi] range.
+// Hashmaps are not ordered by hash, so every entry in every shard is checked, which costs O(n).
+func (c *BigCache) GetHashRange(lo, hi uint64) []EntryInfo {
+	var entries []EntryInfo
+	for _, shard := range c.shards {
+		entries = append(entries, c.copyEntries(shard, func(hashedKey uint64) bool {
+			return hashedKey >= lo && hashedKey <= hi
+		})...)
+	}
+	return entries
+}
+
+// RecentKeys returns up to n most recently set keys, newest first.
+// Queue keeps no links to previous entries, so every shard is scanned from its oldest entry
+// under the read lock keeping only its last n live entries, which are then merged by timestamp.
+// Entries with the same timestamp keep insertion order within a shard, order between shards is arbitrary.
+func (c *BigCache) RecentKeys(n int) []string {
+	if n <= 0 {
+		return nil
+	}
+
+	var recent []EntryInfo
+	for _, shard := range c.shards {
+		recent = append(recent, shard.recentEntries(n)...)
+	}
+	sort.SliceStable(recent, func(i, j int) bool {
+		return recent[i].Timestamp > recent[j].Timestamp
+	})
+
+	if len(recent) > n {
+		recent = recent[:n]
+	}
+	keys := make([]string, len(recent))
+	for i, entry := range recent {
+		keys[i] = entry.Key
+	}
+	return keys
+}
+
+// ShardQueueStats returns internal state of the queue of the shard with given index
+func (c *BigCache) ShardQueueStats(index int) (queue.QueueStats, error) {
+	if err := c.checkShardIndex(index); err != nil {
+		return queue.QueueStats{}, err
+	}
+
+	shard := c.shards[index]
+	shard.lock.RLock()
+	defer shard.lock.RUnlock()
+	return shard.entries.Stats(), nil
+}
+
+// CompactShard rewrites live entries of the shard with given index into a newly allocated queue,
+// dropping space of deleted and overwritten entries and releasing memory the queue grew beyond its initial size.
+// Entries keep their order and timestamps. The shard is locked for writing until compaction is done.
+func (c *BigCache) CompactShard(index int) error {
+	if err := c.checkShardIndex(index); err != nil {
+		return err
+	}
+
+	shard := c.shards[index]
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	c.compact(shard)
+	return nil
+}
+
+// Compact compacts all shards one by one like CompactShard, so only one shard is locked at a time.
+// Config.CompactDeadRatio compacts shards automatically instead.
+func (c *BigCache) Compact() {
+	for i := range c.shards {
+		c.CompactShard(i)
+	}
+}
+
+// compactIfNeeded compacts the shard when dead entries take more than Config.CompactDeadRatio of its used bytes
+// or when its queue stayed underused with Config.ShrinkEnabled. Shards with dead entries smaller than half
+// of the initial shard size are not compacted for dead entries, as dead entries of a shard which did not grow
+// are reclaimed by eviction without allocating memory. Shard must be locked.
+func (c *BigCache) compactIfNeeded(shard *cacheShard) {
+	if shard.entries.ShouldShrink() {
+		c.compact(shard)
+		return
+	}
+	if c.config.CompactDeadRatio <= 0 || shard.deadBytes < c.initialShardSize/2 {
+		return
+	}
+	if float64(shard.deadBytes) > c.config.CompactDeadRatio*float64(shard.entries.Used()) {
+		c.compact(shard)
+	}
+}
+
+// compact rewrites live entries of the shard into a new queue, shard must be locked
+func (c *BigCache) compact(shard *cacheShard) {
+	atomic.AddInt64(&shard.stats.Compactions, 1)
+	var live [][]byte
+	shard.entries.Iterate(func(index int, data []byte) bool {
+		if shard.hashmap[readHashFromEntry(data)] == uint64(index) {
+			live = append(live, copyBytes(data))
+		}
+		return true
+	})
+
+	shard.entries.Reset(c.initialShardSize)
+	shard.deadBytes = 0
+	shard.chains = nil
+	for _, wrappedEntry := range live {
+		hashedKey := readHashFromEntry(wrappedEntry)
+		index, err := shard.entries.Push(wrappedEntry)
+		if err != nil {
+			c.deleteIndex(shard, wrappedEntry)
+			continue
+		}
+		c.chain(shard, wrappedEntry)
+		c.putIndex(shard, hashedKey, uint64(index))
+	}
+}
+
+// EvictToSize evicts the oldest entries across all shards until bytes used by entries drop to targetBytes
+// and returns number of evicted entries. The globally oldest entry is found by peeking heads of all shards
+// before every eviction, so it costs O(shards) per evicted entry. Allocated memory is kept for new entries.
+func (c *BigCache) EvictToSize(targetBytes uint64) (evicted int) {
+	batches := make(map[*cacheShard][]RemovedEntry)
+	var used uint64
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		used += uint64(shard.entries.Used())
+		shard.lock.RUnlock()
+	}
+
+	for used > targetBytes {
+		shard := c.shardWithOldestEntry()
+		if shard == nil {
+			break
+		}
+
+		shard.lock.Lock()
+		usedBefore, sizeBefore := shard.entries.Used(), len(shard.hashmap)
+		if c.config.OnRemoveBatch != nil {
+			if removed, ok := c.copyOldestEntry(shard); ok {
+				batches[shard] = append(batches[shard], removed)
+			}
+			c.popOldestEntry(shard)
+		} else {
+			c.removeOldestEntry(shard, NoSpace)
+		}
+		evicted += sizeBefore - len(shard.hashmap)
+		used -= uint64(usedBefore - shard.entries.Used())
+		shard.lock.Unlock()
+	}
+	c.notifyTransitions()
+	for _, shard := range c.shards {
+		if batch, ok := batches[shard]; ok {
+			c.config.OnRemoveBatch(batch)
+		}
+	}
+	return evicted
+}
+
+// copyOldestEntry returns copy of the oldest entry of the shard if it is live
+func (c *BigCache) copyOldestEntry(shard *cacheShard) (RemovedEntry, bool) {
+	oldestEntry, err := shard.entries.Peek()
+	if err != nil {
+		return RemovedEntry{}, false
+	}
+	if _, ok := shard.hashmap[readHashFromEntry(oldestEntry)]; !ok {
+		return RemovedEntry{}, false
+	}
+	value, err := c.readValue(oldestEntry)
+	if err != nil {
+		return RemovedEntry{}, false
+	}
+	return RemovedEntry{Key: readKeyFromEntry(oldestEntry), Value: copyBytes(value)}, true
+}
+
+// TimeToNextEviction returns time left until the oldest entry of some shard outlives its life window
+// and eviction grace period, so it is evicted by the next Set to that shard. It peeks heads of all shards
+// under their read locks. Zero is returned when an entry is already due, ErrCacheEmpty when there are no entries.
+// Time is measured with one second precision of entry timestamps.
+func (c *BigCache) TimeToNextEviction() (time.Duration, error) {
+	currentTimestamp := uint64(c.clock.Epoch())
+	found := false
+	var next uint64
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		if oldestEntry, err := shard.entries.Peek(); err == nil && !isFillerBlob(oldestEntry) {
+			var left uint64
+			age, window := entryAge(currentTimestamp, readTimestampFromEntry(oldestEntry)), c.evictionWindow(shard, oldestEntry)
+			if age < window {
+				left = window - age
+			}
+			if !found || left < next {
+				found, next = true, left
+			}
+		}
+		shard.lock.RUnlock()
+	}
+	if !found {
+		return 0, ErrCacheEmpty
+	}
+	return time.Duration(next) * time.Second, nil
+}
+
+func (c *BigCache) shardWithOldestEntry() *cacheShard {
+	var oldestShard *cacheShard
+	var oldestTimestamp uint64
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		if oldestEntry, err := shard.entries.Peek(); err == nil {
+			if timestamp := readTimestampFromEntry(oldestEntry); oldestShard == nil || timestamp < oldestTimestamp {
+				oldestShard, oldestTimestamp = shard, timestamp
+			}
+		}
+		shard.lock.RUnlock()
+	}
+	return oldestShard
+}
+
+// checkShardIndex returns error when there is no shard with given index
+func (c *BigCache) checkShardIndex(index int) error {
+	if index < 0 || index >= len(c.shards) {
+		return fmt.Errorf("Shard index %d out of range [0, %d)", index, len(c.shards))
+	}
+	return nil
+}
+
+// LargestEntry returns key and size in bytes, including headers, of the largest entry in the cache.
+// It reads all entries under read locks of their shards, so it is meant for diagnostics.
+// Empty key and zero size are returned for an empty cache.
+func (c *BigCache) LargestEntry() (key string, size int) {
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		for _, index := range shard.hashmap {
+			if wrappedEntry, err := shard.entries.Get(int(index)); err == nil && len(wrappedEntry) > size {
+				key, size = readKeyFromEntry(wrappedEntry), len(wrappedEntry)
+			}
+		}
+		shard.lock.RUnlock()
+	}
+	return key, size
+}
+
+// MaxMemory returns max number of bytes shard queues are allowed to allocate together, as limited by HardMaxCacheSize.
+// Zero means no limit. Hashmaps indexing entries are not included.
+func (c *BigCache) MaxMemory() uint64 {
+	return uint64(c.maxShardSize) * uint64(len(c.shards))
+}
+
+// EntrySizeLimit returns size in bytes of the biggest entry Set accepts, as limited by HardMaxCacheSize,
+// MaxEntrySizeHard and MaxEntryShardShare. Entry size includes key, metadata and headers and counts compressed value.
+// Zero means no limit.
+func (c *BigCache) EntrySizeLimit() int {
+	limit := c.maxShardSize
+	if c.config.MaxEntrySizeHard > 0 && (limit == 0 || c.config.MaxEntrySizeHard < limit) {
+		limit = c.config.MaxEntrySizeHard
+	}
+	if c.config.MaxEntryShardShare > 0 && c.maxShardSize > 0 {
+		if share := int(c.config.MaxEntryShardShare * float64(c.maxShardSize)); share < limit {
+			limit = share
+		}
+	}
+	return limit
+}
+
+// ShardCount returns number of shards in the cache
+func (c *BigCache) ShardCount() int {
+	return len(c.shards)
+}
+
+func (c *BigCache) Size() uint64 {
+	return uint64(atomic.LoadInt64(&c.count))
+}
+
+// putIndex stores index of the entry for hashed key in the shard hashmap and keeps count of entries up to date
+func (c *BigCache) putIndex(shard *cacheShard, hashedKey uint64, index uint64) {
+	if _, ok := shard.hashmap[hashedKey]; !ok {
+		atomic.AddInt64(&c.count, 1)
+	}
+	shard.hashmap[hashedKey] = index
+}
+
+// deleteIndex removes hash the entry is indexed under from the shard hashmap and keeps count of entries up to date.
+// It must be called before the entry is marked dead.
+func (c *BigCache) deleteIndex(shard *cacheShard, wrappedEntry []byte) {
+	hashedKey := readHashFromEntry(wrappedEntry)
+	if _, ok := shard.hashmap[hashedKey]; ok {
+		c.unchain(shard, wrappedEntry)
+		atomic.AddInt64(&c.count, -1)
+		delete(shard.hashmap, hashedKey)
+	}
+}
+
+// notifyTransitions calls Config.OnEmpty or Config.OnFirstEntry when the cache became empty or got an entry
+// since the last notification. It must be called after shard locks are released.
+// Transitions happening quickly one after another may be reported once, as only the current state is compared.
+func (c *BigCache) notifyTransitions() {
+	if c.config.OnEmpty == nil && c.config.OnFirstEntry == nil {
+		return
+	}
+	for {
+		reported := atomic.LoadInt32(&c.empty)
+		var empty int32
+		if atomic.LoadInt64(&c.count) == 0 {
+			empty = 1
+		}
+		if reported == empty {
+			return
+		}
+		if atomic.CompareAndSwapInt32(&c.empty, reported, empty) {
+			if empty == 1 && c.config.OnEmpty != nil {
+				c.config.OnEmpty()
+			}
+			if empty == 0 && c.config.OnFirstEntry != nil {
+				c.config.OnFirstEntry()
+			}
+			return
+		}
+	}
 }
 
 func (c *BigCache) getKeyAndValue(shard *cacheShard, hashedKey uint64) (string, []byte, error) {
@@ -174,23 +1452,239 @@ func (c *BigCache) getKeyAndValue(shard *cacheShard, hashedKey uint64) (string,
 
 	entryKey := readKeyFromEntry(wrappedEntry)
 
-	return entryKey, readEntry(wrappedEntry), nil
+	value, err := c.readValue(wrappedEntry)
+	return entryKey, value, err
+}
+
+// copyEntries returns copies of entries in the shard which hashed key matches, nil match accepts all entries
+// Values are decompressed after the shard lock is released.
+func (c *BigCache) copyEntries(shard *cacheShard, match func(hashedKey uint64) bool) []EntryInfo {
+	shard.lock.RLock()
+	entries := make([]EntryInfo, 0, len(shard.hashmap))
+	values := make([]storedValue, 0, len(shard.hashmap))
+	for _, itemIndex := range shard.hashmap {
+		wrappedEntry, err := shard.entries.Get(int(itemIndex))
+		if err != nil {
+			continue
+		}
+		if match != nil && !match(homeHash(wrappedEntry)) {
+			continue
+		}
+		info, value := c.copyEntryInfo(wrappedEntry)
+		entries = append(entries, info)
+		values = append(values, value)
+	}
+	shard.lock.RUnlock()
+
+	copied := entries[:0]
+	for i, info := range entries {
+		if info, err := c.withValue(info, values[i]); err == nil {
+			copied = append(copied, info)
+		}
+	}
+	return copied
+}
+
+// recentEntries returns keys and timestamps of up to n newest live entries in the shard, newest first
+func (s *cacheShard) recentEntries(n int) []EntryInfo {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	indexes := make([]int, 0, n)
+	s.entries.Iterate(func(index int, wrappedEntry []byte) bool {
+		if itemIndex, ok := s.hashmap[readHashFromEntry(wrappedEntry)]; ok && int(itemIndex) == index {
+			if len(indexes) == n {
+				indexes = append(indexes[:0], indexes[1:]...)
+			}
+			indexes = append(indexes, index)
+		}
+		return true
+	})
+
+	recent := make([]EntryInfo, 0, len(indexes))
+	for i := len(indexes) - 1; i >= 0; i-- {
+		wrappedEntry, _ := s.entries.Get(indexes[i])
+		recent = append(recent, EntryInfo{
+			Key:       readKeyFromEntry(wrappedEntry),
+			Hash:      homeHash(wrappedEntry),
+			Timestamp: readTimestampFromEntry(wrappedEntry),
+		})
+	}
+	return recent
+}
+
+// removeOldestEntry pops the oldest entry of the shard and reports it to Config.OnRemoveWithReason if it was live.
+// Returns false when the shard is empty.
+func (c *BigCache) removeOldestEntry(shard *cacheShard, reason RemoveReason) bool {
+	liveEntry, ok := c.popOldestEntry(shard)
+	if liveEntry != nil {
+		c.notifyRemoval(liveEntry, reason)
+	}
+	return ok
+}
+
+// popOldestEntry pops the oldest entry of the shard and returns it when it was live.
+// Returned entry stays valid until the next push to the shard. Returns false when the shard is empty.
+func (c *BigCache) popOldestEntry(shard *cacheShard) ([]byte, bool) {
+	oldestEntry, err := shard.entries.Pop()
+	if err != nil {
+		return nil, false
+	}
+	hash := readHashFromEntry(oldestEntry)
+	if _, ok := shard.hashmap[hash]; !ok {
+		shard.deadBytes = max(shard.deadBytes-len(oldestEntry), 0)
+		return nil, true
+	}
+	atomic.AddInt64(&shard.stats.Evictions, 1)
+	c.recordEvictedAge(shard, oldestEntry)
+	c.deleteIndex(shard, oldestEntry)
+	return oldestEntry, true
 }
 
-func (c *BigCache) onEvict(oldestEntry []byte, currentTimestamp uint64, evict func()) {
-	oldestTimestamp := readTimestampFromEntry(oldestEntry)
-	if currentTimestamp-oldestTimestamp > c.lifeWindow {
+// notifyRemoval passes removed entry to Config.OnRemoveWithReason
+func (c *BigCache) notifyRemoval(wrappedEntry []byte, reason RemoveReason) {
+	if c.config.OnRemoveWithReason == nil {
+		return
+	}
+	value, err := c.readValue(wrappedEntry)
+	if err != nil {
+		return
+	}
+	c.config.OnRemoveWithReason(readKeyFromEntry(wrappedEntry), value, reason)
+}
+
+func (c *BigCache) onEvict(shard *cacheShard, oldestEntry []byte, currentTimestamp uint64, evict func()) {
+	if c.dueForEviction(shard, oldestEntry, currentTimestamp) {
 		evict()
 	}
 }
 
+// dueForEviction checks whether the oldest entry of the shard outlived its eviction window.
+// Filler blob at the head is always due, so no header field is read from it.
+func (c *BigCache) dueForEviction(shard *cacheShard, oldestEntry []byte, currentTimestamp uint64) bool {
+	if isFillerBlob(oldestEntry) {
+		return true
+	}
+	return entryAge(currentTimestamp, readTimestampFromEntry(oldestEntry)) > c.evictionWindow(shard, oldestEntry)
+}
+
+func (c *BigCache) normalizeKey(key string) string {
+	if c.config.KeyNormalizer != nil {
+		key = c.config.KeyNormalizer(key)
+	}
+	if c.config.CaseInsensitiveKeys {
+		return strings.ToLower(key)
+	}
+	return key
+}
+
+// checkKey verifies that the cache is not closed and the key is accepted.
+// Every operation taking a key calls it, which makes them fail with ErrCacheClosed after Close.
+func (c *BigCache) checkKey(key string) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrCacheClosed
+	}
+	if c.config.RejectEmptyKeys && key == "" {
+		return ErrEmptyKey
+	}
+	return nil
+}
+
+// evictionWindow returns age after which entries of the shard are evicted.
+// With AdaptiveTTL it is the life window (with grace period) as long as the shard uses at most
+// half of its max size, above that it shrinks linearly down to zero when the shard is full.
+func (c *BigCache) evictionWindow(shard *cacheShard, wrappedEntry []byte) uint64 {
+	window := c.entryLifeWindow(wrappedEntry) + c.grace
+	if !c.config.AdaptiveTTL || c.maxShardSize == 0 {
+		return window
+	}
+
+	utilization := float64(shard.entries.Used()) / float64(c.maxShardSize)
+	if utilization <= adaptiveTTLThreshold {
+		return window
+	}
+	if utilization >= 1 {
+		return 0
+	}
+	return uint64(float64(window) * (1 - utilization) / (1 - adaptiveTTLThreshold))
+}
+
+// checkEntrySize verifies that wrapped entry of given size can be stored in a shard
+func (c *BigCache) checkEntrySize(entrySize int) error {
+	if c.maxShardSize > 0 && entrySize > c.maxShardSize {
+		return ErrEntryTooLarge
+	}
+	if c.config.MaxEntrySizeHard > 0 && entrySize > c.config.MaxEntrySizeHard {
+		return fmt.Errorf("%w: %d bytes exceed MaxEntrySizeHard of %d bytes", ErrEntryTooLarge, entrySize, c.config.MaxEntrySizeHard)
+	}
+	if c.config.MaxEntryShardShare > 0 && c.maxShardSize > 0 && float64(entrySize) > c.config.MaxEntryShardShare*float64(c.maxShardSize) {
+		return ErrEntryExceedsShardShare
+	}
+	return nil
+}
+
+// checkExpired returns error matching ErrEntryExpired when entry outlived its life window and eviction grace period
+func (c *BigCache) checkExpired(key string, wrappedEntry []byte) error {
+	if entryAge(uint64(c.clock.Epoch()), readTimestampFromEntry(wrappedEntry)) > c.entryLifeWindow(wrappedEntry)+c.grace {
+		return expired(key)
+	}
+	return nil
+}
+
+// isExpired checks whether entry outlived its life window
+func (c *BigCache) isExpired(wrappedEntry []byte, currentTimestamp uint64) bool {
+	return entryAge(currentTimestamp, readTimestampFromEntry(wrappedEntry)) > c.entryLifeWindow(wrappedEntry)
+}
+
+// entryLifeWindow returns time to live of the entry when it has its own one. Otherwise it returns warm life window
+// for entries promoted to the warm tier and life window for others, multiplied by one plus priority of the entry
+func (c *BigCache) entryLifeWindow(wrappedEntry []byte) uint64 {
+	return c.lifeWindowOf(readTTLFromEntry(wrappedEntry), readFlagsFromEntry(wrappedEntry), readPriorityFromEntry(wrappedEntry))
+}
+
+// lifeWindowOf returns life window in seconds of an entry with given header fields
+func (c *BigCache) lifeWindowOf(ttl uint32, flags byte, priority uint8) uint64 {
+	if ttl != 0 {
+		return uint64(ttl)
+	}
+	window := c.lifeWindow
+	if flags&flagPromoted != 0 {
+		window = c.warmLifeWindow
+	}
+	return window * (1 + uint64(priority))
+}
+
+func convertMBToBytes(value int) int {
+	return value * 1024 * 1024
+}
+
+// entryAge returns zero for entries which timestamp was moved past current time
+func entryAge(currentTimestamp, entryTimestamp uint64) uint64 {
+	if entryTimestamp > currentTimestamp {
+		return 0
+	}
+	return currentTimestamp - entryTimestamp
+}
+
 func (c *BigCache) getShard(hashedKey uint64) (shard *cacheShard) {
 	return c.shards[hashedKey&c.shardMask]
 }
 
+func copyBytes(data []byte) []byte {
+	dst := make([]byte, len(data))
+	copy(dst, data)
+	return dst
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
 	}
 	return b
 }
+
+// markDead resets key of the entry removed from the index and counts its bytes as dead until it is popped
+func (shard *cacheShard) markDead(wrappedEntry []byte) {
+	resetKeyFromEntry(wrappedEntry)
+	shard.deadBytes += len(wrappedEntry)
+}
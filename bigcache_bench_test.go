@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/rand"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -39,8 +40,33 @@ func BenchmarkReadFromCacheWith8192Shards(b *testing.B) {
 	readFromCache(b, 8192)
 }
 
+func BenchmarkIterateShardWith1024Shards(b *testing.B) {
+	cache := cacheForIteration(1024)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for shardIndex := 0; shardIndex < cache.ShardCount(); shardIndex++ {
+			cache.IterateShard(shardIndex, func(key string, value []byte) {})
+		}
+	}
+}
+
+func BenchmarkIterateKeysWith1024Shards(b *testing.B) {
+	cache := cacheForIteration(1024)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		cache.IterateKeys(func(key string) bool { return true })
+	}
+}
+
 func writeToCache(b *testing.B, shards int, lifeWindow time.Duration, requestsInLifeWindow int) {
-	cache, _ := NewBigCache(Config{shards, lifeWindow, max(requestsInLifeWindow, 100), 500, false, nil})
+	cache, _ := NewBigCache(Config{
+		Shards:             shards,
+		LifeWindow:         lifeWindow,
+		MaxEntriesInWindow: max(requestsInLifeWindow, 100),
+		MaxEntrySize:       500,
+	})
 	rand.Seed(time.Now().Unix())
 
 	b.RunParallel(func(pb *testing.PB) {
@@ -54,7 +80,12 @@ func writeToCache(b *testing.B, shards int, lifeWindow time.Duration, requestsIn
 }
 
 func readFromCache(b *testing.B, shards int) {
-	cache, _ := NewBigCache(Config{8192, 1000 * time.Second, max(b.N, 100), 500, false, nil})
+	cache, _ := NewBigCache(Config{
+		Shards:             8192,
+		LifeWindow:         1000 * time.Second,
+		MaxEntriesInWindow: max(b.N, 100),
+		MaxEntrySize:       500,
+	})
 	for i := 0; i < b.N; i++ {
 		cache.Set(strconv.Itoa(i), message)
 	}
@@ -66,3 +97,32 @@ func readFromCache(b *testing.B, shards int) {
 		}
 	})
 }
+
+func cacheForIteration(shards int) *BigCache {
+	cache, _ := NewBigCache(Config{
+		Shards:             shards,
+		LifeWindow:         1000 * time.Second,
+		MaxEntriesInWindow: 10000,
+		MaxEntrySize:       500,
+	})
+	for i := 0; i < 10000; i++ {
+		cache.Set(strconv.Itoa(i), message)
+	}
+	return cache
+}
+
+func BenchmarkFNVHasherLongKey(b *testing.B) {
+	benchmarkHasher(b, NewFNVHasher())
+}
+
+func BenchmarkXXHasherLongKey(b *testing.B) {
+	benchmarkHasher(b, NewXXHasher())
+}
+
+func benchmarkHasher(b *testing.B, hasher Hasher) {
+	key := strings.Repeat("key", 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		hasher.Sum64(key)
+	}
+}
@@ -1,6 +1,12 @@
 package bigcache
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -11,7 +17,12 @@ func TestWriteAndGetOnCache(t *testing.T) {
 	t.Parallel()
 
 	// given
-	cache, _ := NewBigCache(Config{16, 5 * time.Second, 10, 256, false, nil})
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
 	value := []byte("value")
 
 	// when
@@ -27,7 +38,12 @@ func TestConstructCacheWithDefaultHasher(t *testing.T) {
 	t.Parallel()
 
 	// given
-	cache, _ := NewBigCache(Config{16, 5 * time.Second, 10, 256, false, nil})
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
 
 	assert.IsType(t, fnv64a{}, cache.hash)
 }
@@ -36,40 +52,1354 @@ func TestWillReturnErrorOnInvalidNumberOfPartitions(t *testing.T) {
 	t.Parallel()
 
 	// given
-	cache, error := NewBigCache(Config{18, 5 * time.Second, 10, 256, false, nil})
+	cache, error := NewBigCache(Config{
+		Shards:             18,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
 
 	assert.Nil(t, cache)
 	assert.Error(t, error, "Shards number must be power of two")
 }
 
+func TestWillReturnErrorOnZeroShards(t *testing.T) {
+	t.Parallel()
+
+	// when
+	cache, err := NewBigCache(Config{
+		Shards:             0,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// then
+	assert.Nil(t, cache)
+	assert.EqualError(t, err, "Invalid config: Shards number must be power of two, got 0")
+}
+
+func TestConfigValidateReportsAllProblems(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := Config{
+		Shards:     3,
+		LifeWindow: -time.Second,
+	}
+
+	// when
+	cache, err := NewBigCache(config)
+
+	// then
+	assert.Nil(t, cache)
+	assert.EqualError(t, err, "Invalid config: Shards number must be power of two, got 3; "+
+		"MaxEntrySize must be positive, got 0; MaxEntriesInWindow must be positive, got 0; LifeWindow must not be negative, got -1s")
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	assert.NoError(t, DefaultConfig(time.Second).Validate())
+}
+
+func TestGetWithCopy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	aliased, _ := cache.Get("key")
+	copied, err := cache.GetWithCopy("key")
+	cache.Clear()
+	cache.Set("key", []byte("other"))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), copied)
+	assert.Equal(t, []byte("other"), aliased)
+}
+
 func TestEntryNotFound(t *testing.T) {
 	t.Parallel()
 
 	// given
-	cache, _ := NewBigCache(Config{16, 5 * time.Second, 10, 256, false, nil})
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	_, err := cache.Get("nonExistingKey")
+
+	// then
+	assert.EqualError(t, err, "Entry \"nonExistingKey\" not found")
+	var notFoundErr *EntryNotFoundError
+	assert.True(t, errors.As(err, &notFoundErr))
+	assert.Equal(t, "nonExistingKey", notFoundErr.Key)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+}
+
+func TestSetReturnsErrShardFullWhenQueueCannotMakeRoom(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+	})
+	value := make([]byte, 1024*1024-headersSizeInBytes-len("key"))
+
+	// when
+	err := cache.Set("key", value)
+
+	// then
+	assert.Equal(t, ErrShardFull, err)
+}
+
+func TestDelete(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	err := cache.Delete("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.False(t, cache.Has("key"))
+	assert.EqualError(t, cache.Delete("key"), "Entry \"key\" not found")
+	assert.Equal(t, uint64(0), cache.Size())
+}
+
+func TestGetMultiAndSetMulti(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		RejectEmptyKeys:    true,
+	})
+	entries := map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+		"key-3": []byte("value-3"),
+		"":      []byte("rejected"),
+	}
+
+	// when
+	err := cache.SetMulti(entries)
+	values := cache.GetMulti([]string{"key-1", "key-2", "key-3", "missing", ""})
+
+	// then
+	assert.Equal(t, ErrEmptyKey, err)
+	assert.Equal(t, map[string][]byte{
+		"key-1": []byte("value-1"),
+		"key-2": []byte("value-2"),
+		"key-3": []byte("value-3"),
+	}, values)
+	assert.Equal(t, uint64(3), cache.Size())
+}
+
+func TestResetAndRelease(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	initialCapacity := cache.shards[0].entries.Capacity()
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), make([]byte, 256))
+	}
+	grownCapacity := cache.shards[0].entries.Capacity()
+
+	// when
+	cache.ResetAndRelease()
+	cache.Set("key", []byte("value"))
+
+	// then
+	assert.True(t, grownCapacity > initialCapacity)
+	assert.Equal(t, initialCapacity, cache.shards[0].entries.Capacity())
+	assert.Equal(t, uint64(1), cache.Size())
+	assert.False(t, cache.Has("key-0"))
+}
+
+func TestAppend(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	var wg sync.WaitGroup
+
+	// when
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				cache.Append("key", []byte("x"))
+			}
+		}()
+	}
+	wg.Wait()
+	cachedValue, err := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Len(t, cachedValue, 100)
+}
+
+func TestAppendBounded(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	cache.AppendBounded("key", []byte("abc"), 5)
+	cache.AppendBounded("key", []byte("def"), 5)
+	cachedValue, err := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("bcdef"), cachedValue)
+	assert.Error(t, cache.AppendBounded("key", []byte("x"), 0))
+}
+
+func TestBeforeSet(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		BeforeSet: func(key string, value []byte) ([]byte, error) {
+			if len(value) == 0 {
+				return nil, errors.New("empty value")
+			}
+			return bytes.ToUpper(value), nil
+		},
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+	setErr := cache.Set("empty", []byte{})
+	cachedValue, err := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("VALUE"), cachedValue)
+	assert.EqualError(t, setErr, "empty value")
+	assert.False(t, cache.Has("empty"))
+}
+
+func TestSetAfterQueueGrowsWhileWrapped(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         2 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       20,
+	}, &clock)
+	cache.Set("a", nil)
+	cache.Set("b", nil)
+	clock.set(2)
+	cache.Set("c", nil)
+	cache.Set("d", nil)
+	clock.set(3)
+	cache.cleanUp(3)
+	// "e" wraps the queue leaving less than a header between tail and head,
+	// so growing the queue for "f" leaves a filler blob shorter than entry headers behind "e"
+	cache.Set("e", make([]byte, 4))
+	cache.Set("f", nil)
+	clock.set(6)
+
+	// when
+	setErr := cache.Set("g", nil)
+	fillerErr := cache.Set("h", nil)
+	_, nextErr := cache.TimeToNextEviction()
+
+	// then
+	assert.NoError(t, setErr)
+	assert.NoError(t, fillerErr)
+	assert.NoError(t, nextErr)
+	assert.True(t, cache.Has("h"))
+	assert.Equal(t, 400, cache.shards[0].entries.Capacity())
+}
+
+func TestOnShardGrow(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var grownShards []int
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       1,
+		Hasher:             hashStub(1),
+		OnShardGrow: func(shardIndex int, newCapacity int) {
+			grownShards = append(grownShards, shardIndex)
+		},
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+
+	// then
+	assert.Equal(t, []int{1}, grownShards)
+}
+
+func TestCaseInsensitiveKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:              16,
+		LifeWindow:          5 * time.Second,
+		MaxEntriesInWindow:  10,
+		MaxEntrySize:        256,
+		CaseInsensitiveKeys: true,
+	})
+
+	// when
+	cache.Set("Foo", []byte("value"))
+	cachedValue, err := cache.Get("fOO")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+	assert.True(t, cache.Has("FOO"))
+	assert.NoError(t, cache.Delete("foo"))
+	assert.False(t, cache.Has("Foo"))
+}
+
+func TestKeyNormalizer(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		KeyNormalizer:      strings.TrimSpace,
+	})
+
+	// when
+	cache.Set(" key ", []byte("value"))
+	cachedValue, err := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+	assert.True(t, cache.Has("key\t"))
+	assert.Equal(t, []string{"key"}, cache.RecentKeys(1))
+	assert.NoError(t, cache.Delete("  key"))
+	assert.False(t, cache.Has("key"))
+}
+
+func TestRejectEmptyKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		RejectEmptyKeys:    true,
+	})
+
+	// when
+	setErr := cache.Set("", []byte("value"))
+	_, getErr := cache.Get("")
+	deleteErr := cache.Delete("")
+
+	// then
+	assert.Equal(t, ErrEmptyKey, setErr)
+	assert.Equal(t, ErrEmptyKey, getErr)
+	assert.Equal(t, ErrEmptyKey, deleteErr)
+	assert.False(t, cache.Has(""))
+}
+
+func TestOnReallocation(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var reallocations [][]int
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       1,
+		Hasher:             hashStub(1),
+		OnReallocation: func(shardIndex, oldCapacity, newCapacity int, _ time.Duration) {
+			reallocations = append(reallocations, []int{shardIndex, oldCapacity, newCapacity})
+		},
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+
+	// then
+	assert.Len(t, reallocations, 1)
+	assert.Equal(t, 1, reallocations[0][0])
+	assert.True(t, reallocations[0][2] > reallocations[0][1])
+}
+
+func TestClearAsync(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), []byte("value"))
+	}
+
+	// when
+	<-cache.ClearAsync()
+
+	// then
+	assert.Equal(t, uint64(0), cache.Size())
+	assert.False(t, cache.Has("key-0"))
+}
+
+func TestShardQueueStats(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hashStub(1),
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	stats, err := cache.ShardQueueStats(1)
+	_, invalidErr := cache.ShardQueueStats(2)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.Count)
+	assert.Equal(t, 1, stats.Head)
+	assert.Error(t, invalidErr)
+}
+
+func TestCompactShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       64,
+	})
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), bytes.Repeat([]byte("a"), 64))
+	}
+	for i := 1; i < 100; i++ {
+		cache.Delete(fmt.Sprintf("key%d", i))
+	}
+	cache.Set("key0", []byte("value"))
+	cache.Set("key1", []byte("value1"))
+	capacityBefore, _ := cache.ShardQueueStats(0)
+
+	// when
+	err := cache.CompactShard(0)
+	invalidErr := cache.CompactShard(1)
+
+	// then
+	assert.NoError(t, err)
+	assert.EqualError(t, invalidErr, "Shard index 1 out of range [0, 1)")
+	stats, _ := cache.ShardQueueStats(0)
+	assert.Equal(t, 2, stats.Count)
+	assert.True(t, stats.Capacity < capacityBefore.Capacity)
+	value0, _ := cache.Get("key0")
+	value1, _ := cache.Get("key1")
+	assert.Equal(t, []byte("value"), value0)
+	assert.Equal(t, []byte("value1"), value1)
+	assert.Equal(t, []string{"key1", "key0"}, cache.RecentKeys(2))
+}
+
+func TestCompactDeadRatio(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       64,
+		CompactDeadRatio:   0.5,
+	})
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), bytes.Repeat([]byte("a"), 64))
+	}
+	usedBefore := cache.ShardStats()[0].UsedBytes
+
+	// when
+	for i := 1; i < 100; i++ {
+		cache.Delete(fmt.Sprintf("key%d", i))
+	}
+
+	// then
+	shard := cache.ShardStats()[0]
+	assert.True(t, cache.Stats().Compactions > 0)
+	assert.True(t, shard.UsedBytes < usedBefore/2)
+	assert.True(t, shard.DeadBytes < shard.UsedBytes)
+	assert.Equal(t, 1, shard.Entries)
+	value, _ := cache.Get("key0")
+	assert.Equal(t, bytes.Repeat([]byte("a"), 64), value)
+}
+
+func TestShrinkEnabled(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       64,
+		ShrinkEnabled:      true,
+	}, &clock)
+	initialCapacity := cache.ShardStats()[0].Capacity
+	for i := 0; i < 2000; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), bytes.Repeat([]byte("a"), 64))
+	}
+	grownCapacity := cache.ShardStats()[0].Capacity
+
+	// when
+	clock.set(10)
+	cache.cleanUp(uint64(clock.Epoch()))
+	for i := 0; i < shrinkAfterOperations; i++ {
+		clock.set(int64(20 + 10*i))
+		cache.Set("key", []byte("value"))
+	}
+
+	// then
+	assert.True(t, grownCapacity > initialCapacity)
+	assert.Equal(t, initialCapacity, cache.ShardStats()[0].Capacity)
+	assert.Equal(t, int64(1), cache.Stats().Compactions)
+	value, _ := cache.Get("key")
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestDeadBytes(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	cache.Set("other", []byte("value"))
+
+	// when
+	cache.Set("key", []byte("value2"))
+	cache.Delete("other")
+	deadBytes := cache.ShardStats()[0].DeadBytes
+	cache.Compact()
+
+	// then
+	assert.Equal(t, 2*headersSizeInBytes+len("key")+len("other")+2*len("value"), deadBytes)
+	assert.Equal(t, 0, cache.ShardStats()[0].DeadBytes)
+	assert.Equal(t, int64(1), cache.Stats().Compactions)
+}
+
+func TestOnEmptyAndOnFirstEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var transitions []string
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OnEmpty: func() {
+			transitions = append(transitions, "empty")
+		},
+		OnFirstEntry: func() {
+			transitions = append(transitions, "first")
+		},
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+	cache.Set("key2", []byte("value"))
+	cache.Set("key", []byte("value2"))
+	cache.Delete("key")
+	cache.Delete("key2")
+	cache.Set("key", []byte("value"))
+	cache.Clear()
+
+	// then
+	assert.Equal(t, []string{"first", "empty", "first", "empty"}, transitions)
+	assert.Equal(t, uint64(0), cache.Size())
+}
+
+func TestMaxMemory(t *testing.T) {
+	t.Parallel()
+
+	// given
+	bounded, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   2,
+	})
+	unbounded, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// then
+	assert.Equal(t, uint64(2*1024*1024), bounded.MaxMemory())
+	assert.Equal(t, uint64(0), unbounded.MaxMemory())
+}
+
+func TestEntrySizeLimit(t *testing.T) {
+	t.Parallel()
+
+	// given
+	bounded, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   2,
+		MaxEntryShardShare: 0.5,
+	})
+	hardLimited, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   2,
+		MaxEntrySizeHard:   1024,
+	})
+	unbounded, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// then
+	assert.Equal(t, 256*1024, bounded.EntrySizeLimit())
+	assert.Equal(t, 1024, hardLimited.EntrySizeLimit())
+	assert.Equal(t, 0, unbounded.EntrySizeLimit())
+}
+
+func TestMGetWithDeadline(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	cache.Set("key2", []byte("value2"))
+
+	// when
+	values, completed := cache.MGetWithDeadline([]string{"key", "key2", "missing"}, time.Now().Add(time.Minute))
+	expiredValues, expiredCompleted := cache.MGetWithDeadline([]string{"key", "key2"}, time.Now())
+
+	// then
+	assert.True(t, completed)
+	assert.Equal(t, map[string][]byte{"key": []byte("value"), "key2": []byte("value2")}, values)
+	assert.False(t, expiredCompleted)
+	assert.Empty(t, expiredValues)
+}
+
+func TestLargestEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	emptyKey, emptySize := cache.LargestEntry()
+	cache.Set("small", []byte("value"))
+	cache.Set("large", bytes.Repeat([]byte("a"), 100))
+
+	// when
+	key, size := cache.LargestEntry()
+
+	// then
+	assert.Equal(t, "", emptyKey)
+	assert.Equal(t, 0, emptySize)
+	assert.Equal(t, "large", key)
+	assert.Equal(t, headersSizeInBytes+len("large")+100, size)
+}
+
+func TestMaxEntryShardShare(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+		MaxEntryShardShare: 0.25,
+	})
+
+	// when
+	smallErr := cache.Set("small", bytes.Repeat([]byte("a"), 200*1024))
+	largeErr := cache.Set("large", bytes.Repeat([]byte("a"), 300*1024))
+
+	// then
+	assert.NoError(t, smallErr)
+	assert.Equal(t, ErrEntryExceedsShardShare, largeErr)
+	assert.False(t, cache.Has("large"))
+}
+
+func TestMaxEntrySizeHard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntrySizeHard:   headersSizeInBytes + len("key") + 100,
+	})
+
+	// when
+	fittingErr := cache.Set("key", bytes.Repeat([]byte("a"), 100))
+	largeErr := cache.Set("key", bytes.Repeat([]byte("a"), 101))
+
+	// then
+	assert.NoError(t, fittingErr)
+	assert.ErrorIs(t, largeErr, ErrEntryTooLarge)
+	assert.Contains(t, largeErr.Error(), "MaxEntrySizeHard")
+	assert.Equal(t, 1, cache.ShardStats()[0].Entries)
+	value, _ := cache.Get("key")
+	assert.Len(t, value, 100)
+	assert.Equal(t, int64(1), cache.Stats().RejectedSets)
+}
+
+func TestTimeToNextEviction(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	_, emptyErr := cache.TimeToNextEviction()
+	clock.set(2)
+	cache.Set("key", []byte("value"))
+	clock.set(5)
+	cache.Set("key2", []byte("value"))
+
+	// when
+	clock.set(6)
+	left, err := cache.TimeToNextEviction()
+	clock.set(20)
+	due, _ := cache.TimeToNextEviction()
+
+	// then
+	assert.Equal(t, ErrCacheEmpty, emptyErr)
+	assert.NoError(t, err)
+	assert.Equal(t, 6*time.Second, left)
+	assert.Equal(t, time.Duration(0), due)
+}
+
+func TestGetConsistent(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		RejectEmptyKeys:    true,
+	})
+	cache.Set("user:1:name", []byte("name"))
+	cache.Set("user:1:age", []byte("42"))
+
+	// when
+	values, err := cache.GetConsistent([]string{"user:1:name", "user:1:age", "user:1:missing"})
+	_, emptyKeyErr := cache.GetConsistent([]string{"user:1:name", ""})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"user:1:name": []byte("name"), "user:1:age": []byte("42")}, values)
+	assert.Equal(t, ErrEmptyKey, emptyKeyErr)
+}
+
+func TestWouldFit(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+	})
+
+	// when
+	smallSize, smallFits := cache.WouldFit("key", []byte("value"))
+	_, largeFits := cache.WouldFit("key", make([]byte, 2*1024*1024))
+
+	// then
+	assert.Equal(t, headersSizeInBytes+len("key")+len("value"), smallSize)
+	assert.True(t, smallFits)
+	assert.False(t, largeFits)
+	assert.False(t, cache.Has("key"))
+}
+
+func TestTimingEviction(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
+
+	// when
+	cache.Set("key", []byte("value"))
+	clock.set(5)
+	cache.Set("key2", []byte("value2"))
+	_, err := cache.Get("key")
+
+	// then
+	assert.EqualError(t, err, "Entry \"key\" not found")
+}
+
+func TestEvictionGrace(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		EvictionGrace:      5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+
+	// when
+	clock.set(3)
+	cache.Set("key2", []byte("value2"))
+	info, err := cache.GetWithInfo("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.True(t, info.Stale)
+	assert.Equal(t, []byte("value"), info.Value)
+
+	// when
+	clock.set(7)
+	cache.Set("key3", []byte("value3"))
+	_, err = cache.Get("key")
+
+	// then
+	assert.EqualError(t, err, "Entry \"key\" not found")
+}
+
+func TestMaxEntriesPerShardEvictsOldestSetEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 3,
+		EvictionPolicy:     FIFO,
+	})
+
+	// when
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		cache.Set(key, []byte(key))
+	}
+	cache.Set("e", []byte("updated"))
+
+	// then
+	assert.Equal(t, uint64(3), cache.Size())
+	assert.False(t, cache.Has("a"))
+	assert.False(t, cache.Has("b"))
+	assert.True(t, cache.Has("c"))
+	assert.True(t, cache.Has("d"))
+	assert.True(t, cache.Has("e"))
+}
+
+func TestGetAndTouch(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+
+	// when
+	clock.set(4)
+	touchedValue, err := cache.GetAndTouch("key", 4*time.Second)
+	clock.set(8)
+	cache.Set("key2", []byte("value2"))
+	cachedValue, getErr := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), touchedValue)
+	assert.NoError(t, getErr)
+	assert.Equal(t, []byte("value"), cachedValue)
+	_, err = cache.GetAndTouch("missing", time.Second)
+	assert.EqualError(t, err, "Entry \"missing\" not found")
+}
+
+func TestHardMaxCacheSize(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+	})
+	value := make([]byte, 100*1024)
+
+	// when
+	for i := 0; i < 20; i++ {
+		assert.NoError(t, cache.Set(fmt.Sprintf("key-%d", i), value))
+	}
+	err := cache.Set("too-big", make([]byte, 2*1024*1024))
+
+	// then
+	assert.Equal(t, ErrEntryTooLarge, err)
+	assert.True(t, cache.shards[0].entries.Capacity() <= 1024*1024)
+	assert.False(t, cache.Has("key-0"))
+	assert.True(t, cache.Has("key-19"))
+}
+
+func TestAdaptiveTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+		AdaptiveTTL:        true,
+	}, &clock)
+	value := make([]byte, 100*1024)
+	for i := 0; i < 8; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), value)
+	}
+
+	// when
+	clock.set(50)
+	cache.Set("new", []byte("value"))
+
+	// then
+	assert.False(t, cache.Has("key-0"))
+	assert.True(t, cache.Has("key-1"))
+}
+
+func TestEvictToSize(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	for i := 0; i < 10; i++ {
+		clock.set(int64(i))
+		cache.Set(fmt.Sprintf("key-%d", i), make([]byte, 100))
+	}
+
+	// when
+	evicted := cache.EvictToSize(500)
+
+	// then
+	assert.Equal(t, 7, evicted)
+	assert.Equal(t, uint64(3), cache.Size())
+	assert.False(t, cache.Has("key-6"))
+	assert.True(t, cache.Has("key-7"))
+	assert.Equal(t, 0, cache.EvictToSize(500))
+}
+
+func TestOnRemoveWithReason(t *testing.T) {
+	t.Parallel()
+
+	// given
+	type removal struct {
+		key    string
+		value  string
+		reason RemoveReason
+	}
+	var removals []removal
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 2,
+		OnRemoveWithReason: func(key string, entry []byte, reason RemoveReason) {
+			removals = append(removals, removal{key, string(entry), reason})
+		},
+	}, &clock)
+
+	// when
+	cache.Set("expiring", []byte("1"))
+	clock.set(10)
+	cache.Set("replaced", []byte("2"))
+	cache.Set("replaced", []byte("3"))
+	cache.Set("deleted", []byte("4"))
+	cache.Delete("deleted")
+	cache.Set("first", []byte("5"))
+	cache.Set("second", []byte("6"))
+
+	// then
+	assert.Equal(t, []removal{
+		{"expiring", "1", Expired},
+		{"replaced", "2", Replaced},
+		{"deleted", "4", Deleted},
+		{"replaced", "3", NoSpace},
+	}, removals)
+}
+
+func TestOnRemoveBatch(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var batches [][]RemovedEntry
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             2,
+		LifeWindow:         100 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OnRemoveBatch: func(entries []RemovedEntry) {
+			batches = append(batches, entries)
+		},
+	}, &clock)
+	for i := 0; i < 6; i++ {
+		clock.set(int64(i))
+		cache.Set(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	cache.Delete("key-0")
 
 	// when
-	_, err := cache.Get("nonExistingKey")
+	evicted := cache.EvictToSize(0)
 
 	// then
-	assert.EqualError(t, err, "Entry \"nonExistingKey\" not found")
+	assert.Equal(t, 5, evicted)
+	var removed []RemovedEntry
+	for _, batch := range batches {
+		removed = append(removed, batch...)
+	}
+	assert.True(t, len(batches) <= 2)
+	assert.Len(t, removed, 5)
+	assert.Contains(t, removed, RemovedEntry{Key: "key-5", Value: []byte("value-5")})
+	assert.NotContains(t, removed, RemovedEntry{Key: "key-0", Value: []byte("value-0")})
 }
 
-func TestTimingEviction(t *testing.T) {
+func TestPromoteToWarmTier(t *testing.T) {
 	t.Parallel()
 
 	// given
 	clock := mockedClock{value: 0}
-	cache, _ := newBigCache(Config{1, time.Second, 1, 256, false, nil}, &clock)
+	cache, _ := newBigCache(Config{
+		Shards:               1,
+		LifeWindow:           5 * time.Second,
+		WarmLifeWindow:       20 * time.Second,
+		PromoteAfterAccesses: 2,
+		MaxEntriesInWindow:   1,
+		MaxEntrySize:         256,
+	}, &clock)
+	cache.Set("cold", []byte("value"))
+	cache.Set("hot", []byte("value"))
+	cache.Get("hot")
+	cache.Get("hot")
 
 	// when
+	clock.set(10)
+	cache.Set("new", []byte("value"))
+	cache.Set("other", []byte("value"))
+
+	// then
+	assert.False(t, cache.Has("cold"))
+	assert.True(t, cache.Has("hot"))
+}
+
+func TestGetWithInfoReportsRemainingTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         20 * time.Second,
+		EvictionGrace:      10 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
 	cache.Set("key", []byte("value"))
+
+	// when
 	clock.set(5)
-	cache.Set("key2", []byte("value2"))
-	_, err := cache.Get("key")
+	fresh, _ := cache.GetWithInfo("key")
+	clock.set(19)
+	expiring, _ := cache.GetWithInfo("key")
+	clock.set(25)
+	stale, _ := cache.GetWithInfo("key")
 
 	// then
-	assert.EqualError(t, err, "Entry \"key\" not found")
+	assert.Equal(t, 15*time.Second, fresh.TTL)
+	assert.False(t, fresh.ExpiresSoon)
+	assert.Equal(t, time.Second, expiring.TTL)
+	assert.True(t, expiring.ExpiresSoon)
+	assert.False(t, expiring.Stale)
+	assert.Equal(t, time.Duration(0), stale.TTL)
+	assert.True(t, stale.ExpiresSoon)
+	assert.True(t, stale.Stale)
+}
+
+func TestTrackAccessCount(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		TrackAccessCount:   true,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	cache.Get("key")
+	cache.Get("key")
+	info, err := cache.GetWithInfo("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(3), info.Accesses)
+}
+
+func TestReplace(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+	cache.Set("expiring", []byte("value"))
+
+	// when
+	replaced, err := cache.Replace("key", []byte("new value"))
+	cachedValue, _ := cache.Get("key")
+	missingReplaced, missingErr := cache.Replace("missing", []byte("value"))
+	clock.set(10)
+	expiredReplaced, expiredErr := cache.Replace("expiring", []byte("new value"))
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, missingErr)
+	assert.NoError(t, expiredErr)
+	assert.True(t, replaced)
+	assert.False(t, missingReplaced)
+	assert.False(t, expiredReplaced)
+	assert.False(t, cache.Has("missing"))
+	assert.Equal(t, []byte("new value"), cachedValue)
+}
+
+func TestReplaceWithinEvictionGrace(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		EvictionGrace:      5 * time.Second,
+	}, &clock)
+	cache.Set("stale", []byte("value"))
+	cache.Set("expired", []byte("value"))
+
+	// when
+	clock.set(7)
+	staleReplaced, staleErr := cache.Replace("stale", []byte("new value"))
+	cachedValue, _ := cache.Get("stale")
+	clock.set(11)
+	expiredReplaced, expiredErr := cache.Replace("expired", []byte("new value"))
+
+	// then
+	assert.NoError(t, staleErr)
+	assert.NoError(t, expiredErr)
+	assert.True(t, staleReplaced)
+	assert.Equal(t, []byte("new value"), cachedValue)
+	assert.False(t, expiredReplaced)
+}
+
+func TestGetDistinguishesNotFoundFromExpired(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+
+	// when
+	clock.set(6)
+	_, expiredErr := cache.Get("key")
+	_, missingErr := cache.Get("missing")
+
+	// then
+	assert.True(t, errors.Is(expiredErr, ErrEntryExpired))
+	assert.False(t, errors.Is(expiredErr, ErrEntryNotFound))
+	assert.EqualError(t, expiredErr, "Entry \"key\" expired")
+	assert.True(t, errors.Is(missingErr, ErrEntryNotFound))
+	assert.False(t, errors.Is(missingErr, ErrEntryExpired))
+	assert.Equal(t, int64(2), cache.Stats().Misses)
+}
+
+func TestUpdate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       8,
+		MaxEntrySizeHard:   headersSizeInBytes + len("key") + 20,
+	})
+	cache.Set("deleted", []byte("value"))
+	appendByte := func(old []byte, found bool) ([]byte, bool) {
+		return append(old, 'x'), true
+	}
+
+	// when
+	createErr := cache.Update("key", appendByte)
+	appendErr := cache.Update("key", appendByte)
+	deleteErr := cache.Update("deleted", func(old []byte, found bool) ([]byte, bool) {
+		return nil, !found
+	})
+	biggerThanHintErr := cache.Update("other", func(old []byte, found bool) ([]byte, bool) {
+		return []byte("longer than hint"), true
+	})
+	tooLargeErr := cache.Update("key", func(old []byte, found bool) ([]byte, bool) {
+		return []byte("value over hard limit"), true
+	})
+
+	// then
+	assert.NoError(t, createErr)
+	assert.NoError(t, appendErr)
+	assert.NoError(t, deleteErr)
+	assert.NoError(t, biggerThanHintErr)
+	assert.ErrorIs(t, tooLargeErr, ErrEntryTooLarge)
+	cachedValue, _ := cache.Get("key")
+	assert.Equal(t, []byte("xx"), cachedValue)
+	assert.False(t, cache.Has("deleted"))
 }
 
 func TestEntryUpdate(t *testing.T) {
@@ -77,7 +1407,12 @@ func TestEntryUpdate(t *testing.T) {
 
 	// given
 	clock := mockedClock{value: 0}
-	cache, _ := newBigCache(Config{1, 6 * time.Second, 1, 256, false, nil}, &clock)
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         6 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+	}, &clock)
 
 	// when
 	cache.Set("key", []byte("value"))
@@ -95,7 +1430,14 @@ func TestHashCollision(t *testing.T) {
 	t.Parallel()
 
 	// given
-	cache, _ := NewBigCache(Config{16, 5 * time.Second, 10, 256, true, hashStub(5)})
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Verbose:            true,
+		Hasher:             hashStub(5),
+	})
 
 	// when
 	cache.Set("liquid", []byte("value"))
@@ -117,18 +1459,544 @@ func TestHashCollision(t *testing.T) {
 	cachedValue, err = cache.Get("liquid")
 
 	// then
-	assert.Error(t, err)
-	assert.Nil(t, cachedValue)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+}
+
+func TestHashCollisionChain(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxProbeLength:     2,
+		Hasher:             hashStub(5),
+	}, &clock)
+	for i, key := range []string{"a", "b", "c"} {
+		clock.set(int64(i))
+		cache.Set(key, []byte(key))
+	}
+
+	// when
+	clock.set(3)
+	err := cache.Set("d", []byte("d"))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), cache.Size())
+	_, err = cache.Get("a")
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	for _, key := range []string{"b", "c", "d"} {
+		value, err := cache.Get(key)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(key), value)
+	}
+
+	// when
+	cache.Delete("c")
+	cache.Set("b", []byte("b2"))
+	cache.Set("e", []byte("e"))
+
+	// then
+	value, _ := cache.Get("b")
+	assert.Equal(t, []byte("b2"), value)
+	value, _ = cache.Get("e")
+	assert.Equal(t, []byte("e"), value)
+	assert.Equal(t, uint64(3), cache.Size())
+	assert.Len(t, cache.GetHashRange(5, 5), 3)
+}
+
+func TestFullCollisionChain(t *testing.T) {
+	t.Parallel()
+
+	for _, policy := range []ChainFullPolicy{EvictOldestInChain, RejectWhenChainFull} {
+		// given
+		clock := mockedClock{value: 0}
+		cache, _ := newBigCache(Config{
+			Shards:             1,
+			LifeWindow:         time.Minute,
+			MaxEntriesInWindow: 10,
+			MaxEntrySize:       256,
+			MaxProbeLength:     2,
+			OnChainFull:        policy,
+			Hasher:             hashStub(5),
+		}, &clock)
+		for i, key := range []string{"a", "b", "c"} {
+			clock.set(int64(i))
+			assert.NoError(t, cache.Set(key, []byte(key)))
+		}
+
+		// when
+		clock.set(3)
+		err := cache.Set("d", []byte("d"))
+		updateErr := cache.Set("b", []byte("b2"))
+
+		// then
+		assert.NoError(t, updateErr)
+		assert.Equal(t, uint64(3), cache.Size())
+		_, oldestErr := cache.Get("a")
+		_, newErr := cache.Get("d")
+		if policy == EvictOldestInChain {
+			assert.NoError(t, err)
+			assert.ErrorIs(t, oldestErr, ErrEntryNotFound)
+			assert.NoError(t, newErr)
+		} else {
+			assert.ErrorIs(t, err, ErrChainFull)
+			assert.NoError(t, oldestErr)
+			assert.ErrorIs(t, newErr, ErrEntryNotFound)
+			assert.Equal(t, int64(1), cache.Stats().RejectedSets)
+		}
+	}
+}
+func TestIterateShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hashStub(1),
+	})
+	cache.Set("key", []byte("value"))
+	entries := map[string][]byte{}
+
+	// when
+	err := cache.IterateShard(1, func(key string, value []byte) {
+		entries[key] = value
+	})
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"key": []byte("value")}, entries)
+}
+
+func TestIterateShardWithInvalidIndex(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	err := cache.IterateShard(2, func(key string, value []byte) {})
+
+	// then
+	assert.EqualError(t, err, "Shard index 2 out of range [0, 2)")
+}
+
+func TestCorruptedEntryIsDetected(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		VerifyChecksums:    true,
+	})
+	cache.Set("key", []byte("value"))
+	shard := cache.shards[0]
+	wrappedEntry, _ := shard.entries.Get(int(shard.hashmap[cache.hash.Sum64("key")]))
+
+	// when
+	wrappedEntry[len(wrappedEntry)-1] ^= 0xff
+	_, err := cache.Get("key")
+
+	// then
+	assert.True(t, errors.Is(err, ErrCorrupted))
+	assert.EqualError(t, err, "Entry \"key\" corrupted")
+}
+
+func TestCorruptionPolicy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var handledKey string
+	var handledErr error
+	newCorruptedCache := func(policy CorruptionPolicy) *BigCache {
+		cache, _ := NewBigCache(Config{
+			Shards:             1,
+			LifeWindow:         5 * time.Second,
+			MaxEntriesInWindow: 10,
+			MaxEntrySize:       256,
+			VerifyChecksums:    true,
+			OnCorruption:       policy,
+			CorruptionHandler: func(key string, err error) {
+				handledKey, handledErr = key, err
+			},
+		})
+		cache.Set("key", []byte("value"))
+		shard := cache.shards[0]
+		wrappedEntry, _ := shard.entries.Get(int(shard.hashmap[cache.hash.Sum64("key")]))
+		wrappedEntry[len(wrappedEntry)-1] ^= 0xff
+		return cache
+	}
+	panicking := newCorruptedCache(Panic)
+	notifying := newCorruptedCache(Callback)
+
+	// when
+	_, err := notifying.Get("key")
+
+	// then
+	assert.True(t, errors.Is(err, ErrCorrupted))
+	assert.Equal(t, "key", handledKey)
+	assert.True(t, errors.Is(handledErr, ErrCorrupted))
+	assert.Panics(t, func() { panicking.Get("key") })
+}
+
+func TestCorruptedIndexIsDetected(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	shard := cache.shards[0]
+
+	// when
+	shard.hashmap[cache.hash.Sum64("key")] = math.MaxUint32
+	_, err := cache.Get("key")
+
+	// then
+	assert.True(t, errors.Is(err, ErrCorrupted))
+}
+
+func TestIterateKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	for i := 0; i < 10; i++ {
+		cache.Set(fmt.Sprintf("key%d", i), []byte("value"))
+	}
+	cache.Delete("key3")
+
+	// when
+	var keys []string
+	cache.IterateKeys(func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	visited := 0
+	cache.IterateKeys(func(key string) bool {
+		visited++
+		return visited < 3
+	})
+
+	// then
+	assert.ElementsMatch(t, []string{"key0", "key1", "key2", "key4", "key5", "key6", "key7", "key8", "key9"}, keys)
+	assert.Equal(t, 3, visited)
+}
+
+func TestIterateLazy(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("skipped", []byte("value"))
+	cache.Set("read", []byte("value"))
+	cache.Set("removed", []byte("value"))
+	var storedAccessor func() []byte
+
+	// when
+	var keys []string
+	values := map[string][]byte{}
+	cache.IterateLazy(func(key string, value func() []byte) {
+		keys = append(keys, key)
+		switch key {
+		case "read":
+			values[key] = value()
+			storedAccessor = value
+		case "removed":
+			cache.Delete(key)
+			values[key] = value()
+		}
+	})
+
+	// then
+	assert.ElementsMatch(t, []string{"skipped", "read", "removed"}, keys)
+	assert.Equal(t, []byte("value"), values["read"])
+	assert.Nil(t, values["removed"])
+	assert.Nil(t, storedAccessor())
+}
+
+func TestSortedIterate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	for _, key := range []string{"d", "b", "e", "a", "c", "f"} {
+		cache.Set(key, []byte("value-"+key))
+	}
+	var expected []string
+	for shardIndex := 0; shardIndex < 2; shardIndex++ {
+		var keys []string
+		for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+			if cache.hash.Sum64(key)&cache.shardMask == uint64(shardIndex) {
+				keys = append(keys, key)
+			}
+		}
+		expected = append(expected, keys...)
+	}
+
+	// when
+	var keys []string
+	cache.SortedIterate(func(key string, value []byte) {
+		keys = append(keys, key)
+		assert.Equal(t, []byte("value-"+key), value)
+	})
+
+	// then
+	assert.Equal(t, expected, keys)
+}
+
+func TestDumpByAge(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	for i, key := range []string{"a", "b", "c"} {
+		clock.set(int64(i))
+		cache.Set(key, []byte(key))
+	}
+
+	// when
+	oldestFirst := cache.DumpByAge(false)
+	newestFirst := cache.DumpByAge(true)
+
+	// then
+	assert.Equal(t, []string{"a", "b", "c"}, entryKeys(oldestFirst))
+	assert.Equal(t, []string{"c", "b", "a"}, entryKeys(newestFirst))
+	assert.Equal(t, []byte("c"), newestFirst[0].Value)
+	assert.Equal(t, uint64(2), newestFirst[0].Timestamp)
+}
+
+func TestGetHashRange(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	keys := []string{"a", "b", "c", "d", "e"}
+	for _, key := range keys {
+		cache.Set(key, []byte(key))
+	}
+	lo, hi := cache.hash.Sum64("b"), cache.hash.Sum64("d")
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+
+	// when
+	entries := cache.GetHashRange(lo, hi)
+
+	// then
+	for _, key := range keys {
+		hashedKey := cache.hash.Sum64(key)
+		assert.Equal(t, hashedKey >= lo && hashedKey <= hi, containsKey(entries, key), key)
+	}
+}
+
+func containsKey(entries []EntryInfo, key string) bool {
+	for _, entry := range entries {
+		if entry.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRecentKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	for i, key := range []string{"a", "b", "c", "d", "e"} {
+		clock.set(int64(i))
+		cache.Set(key, []byte(key))
+	}
+	cache.Delete("e")
+
+	// when
+	keys := cache.RecentKeys(3)
+
+	// then
+	assert.Equal(t, []string{"d", "c", "b"}, keys)
+}
+
+func entryKeys(entries []EntryInfo) []string {
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.Key
+	}
+	return keys
+}
+
+func TestIndexesAboveMaxUint32AreNotTruncated(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	shard := cache.shards[0]
+	hashedKey := cache.hash.Sum64("key")
+	index := shard.hashmap[hashedKey]
+
+	// when
+	shard.hashmap[hashedKey] = index + math.MaxUint32 + 1
+	_, err := cache.Get("key")
+
+	// then
+	assert.Equal(t, index+math.MaxUint32+1, shard.hashmap[hashedKey])
+	assert.True(t, errors.Is(err, ErrCorrupted))
 }
 
 type mockedClock struct {
 	value int64
 }
 
-func (mc *mockedClock) epoch() int64 {
+func (mc *mockedClock) Epoch() int64 {
 	return mc.value
 }
 
 func (mc *mockedClock) set(value int64) {
 	mc.value = value
 }
+
+func TestIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		IdleTimeout:        5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("read", []byte("value"))
+	cache.Set("idle", []byte("value"))
+
+	// when
+	clock.set(4)
+	cache.Get("read")
+	clock.set(8)
+	readValue, readErr := cache.Get("read")
+	_, idleErr := cache.Get("idle")
+
+	// then
+	assert.NoError(t, readErr)
+	assert.Equal(t, []byte("value"), readValue)
+	assert.True(t, errors.Is(idleErr, ErrEntryExpired))
+}
+
+func TestOverwriteInPlace(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OverwriteInPlace:   true,
+	})
+	cache.Set("key", []byte("value0"))
+	used := cache.ShardStats()[0].UsedBytes
+
+	// when
+	for i := 1; i < 100; i++ {
+		cache.Set("key", []byte(fmt.Sprintf("value%d", i%10)))
+	}
+	sameSizeUsed := cache.ShardStats()[0].UsedBytes
+	cache.Set("key", []byte("longer value"))
+
+	// then
+	value, version, err := cache.GetWithVersion("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("longer value"), value)
+	assert.Equal(t, uint32(101), version)
+	assert.Equal(t, used, sameSizeUsed)
+	assert.True(t, cache.ShardStats()[0].UsedBytes > used)
+	assert.Equal(t, int64(99), cache.Stats().InPlaceOverwrites)
+	assert.Equal(t, 1, cache.ShardStats()[0].Entries)
+}
+
+func TestSlidingExpiration(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		SlidingExpiration:  true,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("read", []byte("value"))
+	cache.Set("idle", []byte("value"))
+
+	// when
+	clock.set(4)
+	cache.Get("read")
+	clock.set(8)
+	readValue, readErr := cache.Get("read")
+	_, idleErr := cache.Get("idle")
+
+	// then
+	assert.NoError(t, readErr)
+	assert.Equal(t, []byte("value"), readValue)
+	assert.True(t, errors.Is(idleErr, ErrEntryExpired))
+}
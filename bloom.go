@@ -0,0 +1,62 @@
+package bigcache
+
+import "sync/atomic"
+
+const bloomFilterHashes = 3 // Number of bits set in bloom filter for every key
+
+// bloomFilter tells when key was certainly never added. Bits are accessed atomically,
+// so the filter can be checked without holding the shard lock.
+type bloomFilter struct {
+	words []uint64
+	bits  uint64
+}
+
+func newBloomFilter(bits int) *bloomFilter {
+	words := (bits + 63) / 64
+	return &bloomFilter{
+		words: make([]uint64, words),
+		bits:  uint64(words * 64),
+	}
+}
+
+func (b *bloomFilter) add(hashedKey uint64) {
+	h1, h2 := bloomHashes(hashedKey)
+	for i := uint64(0); i < bloomFilterHashes; i++ {
+		bit := (h1 + i*h2) % b.bits
+		word, mask := &b.words[bit/64], uint64(1)<<(bit%64)
+		for {
+			old := atomic.LoadUint64(word)
+			if old&mask != 0 || atomic.CompareAndSwapUint64(word, old, old|mask) {
+				break
+			}
+		}
+	}
+}
+
+func (b *bloomFilter) mayContain(hashedKey uint64) bool {
+	h1, h2 := bloomHashes(hashedKey)
+	for i := uint64(0); i < bloomFilterHashes; i++ {
+		bit := (h1 + i*h2) % b.bits
+		if atomic.LoadUint64(&b.words[bit/64])&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *bloomFilter) reset() {
+	for i := range b.words {
+		atomic.StoreUint64(&b.words[i], 0)
+	}
+}
+
+// bloomHashes mixes hashed key, because its lowest bits are the same for all keys in a shard
+func bloomHashes(hashedKey uint64) (uint64, uint64) {
+	h := hashedKey
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+	return h, h>>32 | 1
+}
@@ -0,0 +1,56 @@
+package bigcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	// given
+	filter := newBloomFilter(1024)
+
+	// when
+	for i := uint64(0); i < 50; i++ {
+		filter.add(i << 10)
+	}
+
+	// then
+	for i := uint64(0); i < 50; i++ {
+		assert.True(t, filter.mayContain(i<<10))
+	}
+	filter.reset()
+	assert.False(t, filter.mayContain(0))
+}
+
+func TestCacheWithBloomFilter(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		BloomFilterBits:    4096,
+	})
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), []byte("value"))
+	}
+
+	// when
+	_, err := cache.Get("missing")
+
+	// then
+	assert.EqualError(t, err, "Entry \"missing\" not found")
+	for i := 0; i < 100; i++ {
+		assert.True(t, cache.Has(fmt.Sprintf("key-%d", i)))
+	}
+	cache.Clear()
+	assert.False(t, cache.Has("key-0"))
+	assert.Equal(t, int64(1), cache.Stats().Misses)
+}
@@ -0,0 +1,48 @@
+package bigcache
+
+import (
+	"errors"
+	"strings"
+	"unsafe"
+)
+
+// GetBytesKey reads entry for the key given as bytes, like Get. The key is used in place instead of being
+// converted to a string, so the lookup does not allocate. The key must not be modified until GetBytesKey returns,
+// and hooks receiving the key, e.g. KeyNormalizer, must not keep it. Keys of returned errors and keys passed
+// to Config.Loader by RefreshAhead are copies.
+func (c *BigCache) GetBytesKey(key []byte) ([]byte, error) {
+	value, err := c.Get(bytesToString(key))
+	if err != nil {
+		return nil, detachKey(err)
+	}
+	return value, nil
+}
+
+// SetBytesKey saves entry under the key given as bytes, like Set. The key is copied into the shard
+// without being converted to a string first. The key must not be modified until SetBytesKey returns,
+// and hooks receiving the key, e.g. BeforeSet and KeyNormalizer, must not keep it.
+// Config.Store in WriteBehind mode receives a copy of the key.
+func (c *BigCache) SetBytesKey(key []byte, entry []byte) error {
+	return detachKey(c.Set(bytesToString(key), entry))
+}
+
+// bytesToString returns string sharing memory with b, it must not be kept after b changes
+func bytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b))
+}
+
+// detachKey replaces key kept by typed errors with its copy, so the error does not share memory with key bytes
+func detachKey(err error) error {
+	var notFoundErr *EntryNotFoundError
+	var expiredErr *EntryExpiredError
+	var corruptedErr *CorruptedEntryError
+	switch {
+	case errors.As(err, &notFoundErr):
+		notFoundErr.Key = strings.Clone(notFoundErr.Key)
+	case errors.As(err, &expiredErr):
+		expiredErr.Key = strings.Clone(expiredErr.Key)
+	case errors.As(err, &corruptedErr):
+		corruptedErr.Key = strings.Clone(corruptedErr.Key)
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBytesKey(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	key := []byte("key")
+
+	// when
+	err := cache.SetBytesKey(key, []byte("value"))
+	copy(key, "new")
+
+	// then
+	assert.NoError(t, err)
+	cachedValue, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+	cachedValue, err = cache.GetBytesKey([]byte("key"))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+}
+
+func TestGetBytesKeyErrorDoesNotShareKey(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	key := []byte("missing")
+
+	// when
+	_, err := cache.GetBytesKey(key)
+	copy(key, "changed")
+
+	// then
+	assert.EqualError(t, err, "Entry \"missing\" not found")
+}
+
+func TestGetBytesKeyDoesNotAllocate(t *testing.T) {
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	key := []byte("key")
+	cache.SetBytesKey(key, []byte("value"))
+
+	// when
+	allocs := testing.AllocsPerRun(100, func() {
+		cache.GetBytesKey(key)
+	})
+
+	// then
+	assert.Equal(t, float64(0), allocs)
+}
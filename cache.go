@@ -0,0 +1,16 @@
+package bigcache
+
+// Cache lists public operations of BigCache. Code using the cache can depend on this interface
+// so that BigCache can be replaced with a fake in tests.
+type Cache interface {
+	Get(key string) ([]byte, error)
+	Set(key string, entry []byte) error
+	Delete(key string) error
+	Has(key string) bool
+	Clear()
+	Iterate(accept func(string, []byte))
+	Size() uint64
+	Stats() Stats
+}
+
+var _ Cache = (*BigCache)(nil)
@@ -0,0 +1,127 @@
+package bigcache
+
+import "sync/atomic"
+
+const (
+	defaultMaxProbeLength = 8  // Number of collision chain slots of a hash when Config.MaxProbeLength is not set
+	maxProbeLength        = 15 // Max number of collision chain slots, limited by flag bits keeping the slot number
+	// Distance between collision chain slots. Low 32 bits are zero, so all slots of a hash map to the same shard.
+	probeStride uint64 = 0x9e3779b9 << 32
+)
+
+// Keys with the same hash are chained: the first one is indexed under the hash itself, the home slot,
+// and the following ones under hashes derived from it, probe slots, up to Config.MaxProbeLength of them.
+// Entries keep the hash they are indexed under in the header and the slot number in flags, so code removing
+// entries by hash from the header does not need to know about chains. Shard counts chained entries of every
+// home hash, lookups probe further slots only when the count is not zero, so the common case without
+// collisions costs a single map lookup.
+
+// probeSlot returns hash the probe-th chained entry of home hash is indexed under
+func probeSlot(home uint64, probe int) uint64 {
+	return home + uint64(probe)*probeStride
+}
+
+func readProbeFromEntry(wrappedEntry []byte) int {
+	return int(readFlagsFromEntry(wrappedEntry) >> probeShift)
+}
+
+// homeHash returns hash of the key of the entry
+func homeHash(wrappedEntry []byte) uint64 {
+	return readHashFromEntry(wrappedEntry) - uint64(readProbeFromEntry(wrappedEntry))*probeStride
+}
+
+func (c *BigCache) maxProbeLength() int {
+	if c.config.MaxProbeLength == 0 {
+		return defaultMaxProbeLength
+	}
+	return c.config.MaxProbeLength
+}
+
+// findChained returns chained entry for the key and number of its chain slot, shard must be locked
+func (c *BigCache) findChained(shard *cacheShard, key string, home uint64) ([]byte, int, bool) {
+	if shard.chains[home] == 0 {
+		return nil, 0, false
+	}
+	for probe := 1; probe <= c.maxProbeLength(); probe++ {
+		index, ok := shard.hashmap[probeSlot(home, probe)]
+		if !ok {
+			continue
+		}
+		wrappedEntry, err := shard.entries.Get(int(index))
+		if err == nil && readProbeFromEntry(wrappedEntry) == probe && c.keyMatches(wrappedEntry, key) {
+			return wrappedEntry, probe, true
+		}
+	}
+	return nil, 0, false
+}
+
+// findSlot returns number of the chain slot under which entry for the key should be indexed and index of the current
+// entry for the key, zero when there is none. A key colliding with other keys gets the first free slot of the chain. When all slots
+// are taken, the oldest entry among them is evicted if evictWhenFull is set, otherwise ErrChainFull is returned. Shard must be locked.
+func (c *BigCache) findSlot(shard *cacheShard, key string, home uint64, evictWhenFull bool) (int, uint64, error) {
+	homeIndex, homeTaken := shard.hashmap[home]
+	if homeTaken {
+		if wrappedEntry, err := shard.entries.Get(int(homeIndex)); err != nil || c.keyMatches(wrappedEntry, key) {
+			return 0, homeIndex, nil
+		}
+	}
+	if _, probe, ok := c.findChained(shard, key, home); ok {
+		return probe, shard.hashmap[probeSlot(home, probe)], nil
+	}
+	if !homeTaken {
+		return 0, 0, nil
+	}
+
+	var oldest []byte
+	for probe := 1; probe <= c.maxProbeLength(); probe++ {
+		index, ok := shard.hashmap[probeSlot(home, probe)]
+		if !ok {
+			return probe, 0, nil
+		}
+		if wrappedEntry, err := shard.entries.Get(int(index)); err == nil &&
+			(oldest == nil || readTimestampFromEntry(wrappedEntry) < readTimestampFromEntry(oldest)) {
+			oldest = wrappedEntry
+		}
+	}
+	if homeEntry, err := shard.entries.Get(int(homeIndex)); err == nil &&
+		(oldest == nil || readTimestampFromEntry(homeEntry) < readTimestampFromEntry(oldest)) {
+		oldest = homeEntry
+	}
+	if oldest == nil {
+		return 0, homeIndex, nil
+	}
+	if !evictWhenFull {
+		return 0, 0, ErrChainFull
+	}
+	probe := readProbeFromEntry(oldest)
+	atomic.AddInt64(&shard.stats.Evictions, 1)
+	c.recordEvictedAge(shard, oldest)
+	c.notifyRemoval(oldest, NoSpace)
+	c.deleteIndex(shard, oldest)
+	shard.markDead(oldest)
+	return probe, 0, nil
+}
+
+// chain counts entry indexed under a new probe slot in chains of the shard
+func (c *BigCache) chain(shard *cacheShard, wrappedEntry []byte) {
+	if readProbeFromEntry(wrappedEntry) == 0 {
+		return
+	}
+	if shard.chains == nil {
+		shard.chains = make(map[uint64]int)
+	}
+	shard.chains[homeHash(wrappedEntry)]++
+}
+
+// unchain removes entry indexed under a probe slot from chains of the shard
+func (c *BigCache) unchain(shard *cacheShard, wrappedEntry []byte) {
+	if readProbeFromEntry(wrappedEntry) == 0 {
+		return
+	}
+	home := homeHash(wrappedEntry)
+	if shard.chains[home] > 1 {
+		shard.chains[home]--
+	} else {
+		delete(shard.chains, home)
+	}
+}
@@ -0,0 +1,68 @@
+package bigcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PauseCleanup suspends background removal of expired entries enabled by Config.CleanWindow, e.g. for a bulk
+// migration. The goroutine keeps running but skips its work, so expired entries accumulate until ResumeCleanup.
+// Expired entries are still reported as expired on read and evicted by Set.
+func (c *BigCache) PauseCleanup() {
+	atomic.StoreInt32(&c.cleanupPaused, 1)
+}
+
+// ResumeCleanup resumes background removal of expired entries suspended by PauseCleanup
+func (c *BigCache) ResumeCleanup() {
+	atomic.StoreInt32(&c.cleanupPaused, 0)
+}
+
+// cleanUpLoop runs cleanup on every tick until the cache is closed
+func (c *BigCache) cleanUpLoop(ticks <-chan time.Time) {
+	for {
+		select {
+		case <-ticks:
+			c.periodicCleanUp()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// periodicCleanUp removes expired entries unless cleanup is paused
+func (c *BigCache) periodicCleanUp() {
+	if atomic.LoadInt32(&c.cleanupPaused) == 1 {
+		return
+	}
+	c.cleanUp(uint64(c.clock.Epoch()))
+}
+
+// cleanUp removes entries which outlived their eviction window from heads of all shards.
+// Removed entries of every shard are reported to Config.OnRemoveBatch after the shard lock is released.
+func (c *BigCache) cleanUp(currentTimestamp uint64) {
+	for _, shard := range c.shards {
+		var batch []RemovedEntry
+		shard.lock.Lock()
+		for {
+			oldestEntry, err := shard.entries.Peek()
+			if err != nil || !c.dueForEviction(shard, oldestEntry, currentTimestamp) {
+				break
+			}
+			if c.config.OnRemoveBatch == nil {
+				c.removeOldestEntry(shard, Expired)
+				continue
+			}
+			if removed, ok := c.copyOldestEntry(shard); ok {
+				batch = append(batch, removed)
+			}
+			c.popOldestEntry(shard)
+		}
+		c.compactIfNeeded(shard)
+		shard.lock.Unlock()
+
+		if len(batch) > 0 {
+			c.config.OnRemoveBatch(batch)
+		}
+	}
+	c.notifyTransitions()
+}
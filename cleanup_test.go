@@ -0,0 +1,82 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanUp(t *testing.T) {
+	t.Parallel()
+
+	// given
+	var removed []RemovedEntry
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OnRemoveBatch: func(entries []RemovedEntry) {
+			removed = append(removed, entries...)
+		},
+	}, &clock)
+	cache.Set("old", []byte("value"))
+	clock.set(4)
+	cache.Set("new", []byte("value"))
+
+	// when
+	clock.set(7)
+	cache.periodicCleanUp()
+
+	// then
+	assert.Equal(t, uint64(1), cache.Size())
+	assert.Equal(t, []RemovedEntry{{Key: "old", Value: []byte("value")}}, removed)
+	assert.True(t, cache.Has("new"))
+}
+
+func TestPauseCleanup(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+	clock.set(10)
+
+	// when
+	cache.PauseCleanup()
+	cache.periodicCleanUp()
+	sizeWhenPaused := cache.Size()
+	cache.ResumeCleanup()
+	cache.periodicCleanUp()
+
+	// then
+	assert.Equal(t, uint64(1), sizeWhenPaused)
+	assert.Equal(t, uint64(0), cache.Size())
+}
+
+func TestCleanWindow(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		CleanWindow:        100 * time.Millisecond,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+
+	// then
+	assert.Eventually(t, func() bool { return cache.Size() == 0 }, 5*time.Second, 50*time.Millisecond)
+}
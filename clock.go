@@ -2,13 +2,15 @@ package bigcache
 
 import "time"
 
-type clock interface {
-	epoch() int64
+// Clock provides current time as Unix epoch in seconds. Custom implementation can be passed
+// to NewWithClock, e.g. to control time when testing entries eviction.
+type Clock interface {
+	Epoch() int64
 }
 
 type systemClock struct {
 }
 
-func (c systemClock) epoch() int64 {
+func (c systemClock) Epoch() int64 {
 	return time.Now().Unix()
 }
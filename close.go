@@ -0,0 +1,30 @@
+package bigcache
+
+import "sync/atomic"
+
+// Close stops background goroutines, i.e. CleanWindow cleanup, and releases memory of all shards.
+// Operations taking a key return ErrCacheClosed afterwards, Has reports no entries and Load returns ErrCacheClosed.
+// Entries are dropped without being reported to removal callbacks, Save them before Close to keep them.
+// With Config.Store in WriteBehind mode, Close waits until all queued writes reach the store.
+// It returns ErrCacheClosed when the cache was already closed.
+func (c *BigCache) Close() error {
+	if !atomic.CompareAndSwapInt32(&c.closed, 0, 1) {
+		return ErrCacheClosed
+	}
+	close(c.done)
+	if c.writeBehind != nil {
+		c.writeBehind.close()
+	}
+
+	for _, shard := range c.shards {
+		shard.lock.Lock()
+		shard.entries.Reset(0)
+		shard.deadBytes = 0
+		shard.chains = nil
+		atomic.AddInt64(&c.count, -int64(len(shard.hashmap)))
+		shard.hashmap = make(map[uint64]uint64)
+		shard.entryBuffer = nil
+		shard.lock.Unlock()
+	}
+	return nil
+}
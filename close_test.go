@@ -0,0 +1,65 @@
+package bigcache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClose(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		CleanWindow:        time.Millisecond,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	err := cache.Close()
+
+	// then
+	assert.NoError(t, err)
+	_, getErr := cache.Get("key")
+	assert.Equal(t, ErrCacheClosed, getErr)
+	assert.Equal(t, ErrCacheClosed, cache.Set("key", []byte("value")))
+	assert.Equal(t, ErrCacheClosed, cache.Delete("key"))
+	assert.Equal(t, ErrCacheClosed, cache.Load(&bytes.Buffer{}))
+	assert.False(t, cache.Has("key"))
+	assert.Equal(t, uint64(0), cache.Size())
+	assert.Equal(t, 0, cache.shards[0].entries.Capacity())
+	assert.Equal(t, ErrCacheClosed, cache.Close())
+}
+
+func TestCloseStopsCleanUpLoop(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	stopped := make(chan struct{})
+	go func() {
+		cache.cleanUpLoop(make(chan time.Time))
+		close(stopped)
+	}()
+
+	// when
+	cache.Close()
+
+	// then
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("cleanup loop did not stop")
+	}
+}
@@ -0,0 +1,100 @@
+package bigcache
+
+import (
+	"bytes"
+	"compress/flate"
+	"io/ioutil"
+)
+
+const defaultCompressAbove = 1024 // Size of values compressed when Config.Compressor is set without Config.CompressAbove
+
+// Compressor compresses entries larger than Config.CompressAbove before they are stored
+// and decompresses them when they are read
+type Compressor interface {
+	Compress(data []byte) ([]byte, error)
+	Decompress(data []byte) ([]byte, error)
+}
+
+// FlateCompressor is a Compressor using DEFLATE algorithm from compress/flate, used by default
+type FlateCompressor struct {
+	// Level is passed to flate.NewWriter, zero means flate.DefaultCompression
+	Level int
+}
+
+// Compress returns DEFLATE compressed data
+func (f FlateCompressor) Compress(data []byte) ([]byte, error) {
+	level := f.Level
+	if level == 0 {
+		level = flate.DefaultCompression
+	}
+
+	var buffer bytes.Buffer
+	writer, err := flate.NewWriter(&buffer, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Decompress returns data decompressed with DEFLATE algorithm
+func (f FlateCompressor) Decompress(data []byte) ([]byte, error) {
+	reader := flate.NewReader(bytes.NewReader(data))
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
+
+// storedValue is a value prepared for a shard by compress, so compression runs before the shard is locked
+type storedValue struct {
+	data      []byte
+	flags     byte
+	rawLength int
+}
+
+func (v storedValue) compressed() bool {
+	return v.flags&flagCompressed != 0
+}
+
+// compress returns value to store with its flags. Value is compressed only when it is at least
+// Config.CompressAbove bytes long and compression makes it smaller.
+func (c *BigCache) compress(entry []byte) storedValue {
+	value := storedValue{data: entry, rawLength: len(entry)}
+	if c.config.CompressAbove <= 0 || len(entry) < c.config.CompressAbove {
+		return value
+	}
+	compressed, err := c.config.Compressor.Compress(entry)
+	if err != nil || len(compressed) >= len(entry) {
+		return value
+	}
+	value.data, value.flags = compressed, flagCompressed
+	return value
+}
+
+// readValue returns value of wrapped entry, decompressed when it was stored compressed.
+// Paths which only copy the value out of the shard use copyStoredValue and decompress after unlocking instead.
+func (c *BigCache) readValue(wrappedEntry []byte) ([]byte, error) {
+	value := readEntry(wrappedEntry)
+	if readFlagsFromEntry(wrappedEntry)&flagCompressed == 0 {
+		return value, nil
+	}
+	return c.config.Compressor.Decompress(value)
+}
+
+// copyStoredValue returns copy of the value of wrapped entry as stored, with its flags, shard must be locked.
+// The copy is passed to decompress once the shard lock is released.
+func copyStoredValue(wrappedEntry []byte) storedValue {
+	return storedValue{data: copyBytes(readEntry(wrappedEntry)), flags: readFlagsFromEntry(wrappedEntry) & flagCompressed}
+}
+
+// decompress returns value copied by copyStoredValue, decompressed when it was stored compressed
+func (c *BigCache) decompress(value storedValue) ([]byte, error) {
+	if !value.compressed() {
+		return value.data, nil
+	}
+	return c.config.Compressor.Decompress(value.data)
+}
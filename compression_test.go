@@ -0,0 +1,151 @@
+package bigcache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressAbove(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		CompressAbove:      100,
+	})
+	big := bytes.Repeat([]byte("value"), 100)
+	small := []byte("value")
+
+	// when
+	cache.Set("big", big)
+	cache.Set("small", small)
+	cachedBig, bigErr := cache.Get("big")
+	cachedSmall, smallErr := cache.Get("small")
+
+	// then
+	assert.NoError(t, bigErr)
+	assert.NoError(t, smallErr)
+	assert.Equal(t, big, cachedBig)
+	assert.Equal(t, small, cachedSmall)
+	assert.True(t, storedValueSize(cache, "big") < len(big))
+	assert.Equal(t, len(small), storedValueSize(cache, "small"))
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.CompressedEntries)
+	assert.Equal(t, int64(len(big)), stats.RawBytes)
+	assert.Equal(t, int64(storedValueSize(cache, "big")), stats.CompressedBytes)
+}
+
+func TestCompressorEnablesCompression(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Compressor:         FlateCompressor{},
+	})
+	big := bytes.Repeat([]byte("value"), defaultCompressAbove/len("value")+1)
+	small := bytes.Repeat([]byte("value"), 100)
+
+	// when
+	cache.Set("big", big)
+	cache.Set("small", small)
+	cachedBig, err := cache.Get("big")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, big, cachedBig)
+	assert.True(t, storedValueSize(cache, "big") < len(big))
+	assert.Equal(t, len(small), storedValueSize(cache, "small"))
+}
+
+func TestFlateCompressor(t *testing.T) {
+	// given
+	compressor := FlateCompressor{}
+	data := bytes.Repeat([]byte("data"), 100)
+
+	// when
+	compressed, err := compressor.Compress(data)
+	decompressed, decompressErr := compressor.Decompress(compressed)
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, decompressErr)
+	assert.Equal(t, data, decompressed)
+}
+
+func TestCompressionRunsOutsideShardLock(t *testing.T) {
+	t.Parallel()
+
+	// given
+	compressor := &lockCheckingCompressor{}
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		CompressAbove:      100,
+		Compressor:         compressor,
+	})
+	compressor.shard = cache.shards[0]
+	value := bytes.Repeat([]byte("value"), 100)
+
+	// when
+	setErr := cache.Set("key", value)
+	cached, getErr := cache.Get("key")
+	info, infoErr := cache.GetWithInfo("key")
+
+	// then
+	assert.NoError(t, setErr)
+	assert.NoError(t, getErr)
+	assert.NoError(t, infoErr)
+	assert.Equal(t, value, cached)
+	assert.Equal(t, value, info.Value)
+	assert.Equal(t, 1, compressor.compressed)
+	assert.Equal(t, 2, compressor.decompressed)
+	assert.False(t, compressor.locked)
+}
+
+// lockCheckingCompressor records whether the shard lock was held while it compressed or decompressed
+type lockCheckingCompressor struct {
+	FlateCompressor
+	shard        *cacheShard
+	locked       bool
+	compressed   int
+	decompressed int
+}
+
+func (l *lockCheckingCompressor) Compress(data []byte) ([]byte, error) {
+	l.compressed++
+	l.checkLock()
+	return l.FlateCompressor.Compress(data)
+}
+
+func (l *lockCheckingCompressor) Decompress(data []byte) ([]byte, error) {
+	l.decompressed++
+	l.checkLock()
+	return l.FlateCompressor.Decompress(data)
+}
+
+func (l *lockCheckingCompressor) checkLock() {
+	if !l.shard.lock.TryLock() {
+		l.locked = true
+		return
+	}
+	l.shard.lock.Unlock()
+}
+
+func storedValueSize(cache *BigCache, key string) int {
+	hashedKey := cache.hash.Sum64(key)
+	shard := cache.getShard(hashedKey)
+	wrappedEntry, _ := shard.entries.Get(int(shard.hashmap[hashedKey]))
+	return len(readEntry(wrappedEntry))
+}
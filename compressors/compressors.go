@@ -0,0 +1,60 @@
+// Package compressors provides implementations of bigcache.Compressor faster than the default FlateCompressor.
+//
+//	config.Compressor = compressors.Snappy{} // compresses values of at least 1 KiB unless config.CompressAbove is set
+//
+// Snappy compresses and decompresses fastest, Zstd compresses better at a higher CPU cost. Snapshots written by Save
+// hold decompressed values, so they can be restored with Load by a cache using any compressor.
+package compressors
+
+import (
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/mikaelnousiainen/bigcache"
+)
+
+var (
+	_ bigcache.Compressor = Snappy{}
+	_ bigcache.Compressor = (*Zstd)(nil)
+)
+
+// Snappy is a Compressor using snappy block format
+type Snappy struct{}
+
+// Compress returns snappy compressed data
+func (Snappy) Compress(data []byte) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+// Decompress returns data decompressed with snappy
+func (Snappy) Decompress(data []byte) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+// Zstd is a Compressor using Zstandard algorithm. It is safe for concurrent use by all shards.
+type Zstd struct {
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+}
+
+// NewZstd creates Zstandard compressor with given level, zstd.SpeedDefault is a good balance for most values
+func NewZstd(level zstd.EncoderLevel) (*Zstd, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level))
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Zstd{encoder: encoder, decoder: decoder}, nil
+}
+
+// Compress returns Zstandard compressed data
+func (z *Zstd) Compress(data []byte) ([]byte, error) {
+	return z.encoder.EncodeAll(data, nil), nil
+}
+
+// Decompress returns data decompressed with Zstandard
+func (z *Zstd) Decompress(data []byte) ([]byte, error) {
+	return z.decoder.DecodeAll(data, nil)
+}
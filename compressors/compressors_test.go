@@ -0,0 +1,38 @@
+package compressors
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/mikaelnousiainen/bigcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressors(t *testing.T) {
+	t.Parallel()
+
+	zstdCompressor, err := NewZstd(zstd.SpeedDefault)
+	assert.NoError(t, err)
+	for name, compressor := range map[string]bigcache.Compressor{"snappy": Snappy{}, "zstd": zstdCompressor} {
+		// given
+		cache, _ := bigcache.NewBigCache(bigcache.Config{
+			Shards:             1,
+			LifeWindow:         5 * time.Second,
+			MaxEntriesInWindow: 10,
+			MaxEntrySize:       1024,
+			CompressAbove:      1,
+			Compressor:         compressor,
+		})
+		value := bytes.Repeat([]byte(`{"key":"value"}`), 50)
+
+		// when
+		cache.Set("key", value)
+		cached, err := cache.Get("key")
+
+		// then
+		assert.NoError(t, err, name)
+		assert.Equal(t, value, cached, name)
+	}
+}
@@ -1,6 +1,10 @@
 package bigcache
 
-import "time"
+import (
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Config for BigCache
 type Config struct {
@@ -9,17 +13,235 @@ type Config struct {
 	Shards int
 	// Time after which entry can be evicted
 	LifeWindow time.Duration
+	// IdleTimeout makes entries expire after this long without being read instead of after LifeWindow since they were set.
+	// Every read moves timestamp of the entry to the current time, which takes the shard write lock on reads.
+	// IdleTimeout wins over LifeWindow when both are set, there is no absolute limit of entry age then.
+	// Warm tier and priorities extend it like they extend LifeWindow. Entries are still evicted from the oldest set,
+	// so a frequently read entry set long ago delays eviction of idle entries set after it.
+	IdleTimeout time.Duration
+	// SlidingExpiration makes every read reset the life window of the entry, so frequently read entries stay
+	// while entries not read for LifeWindow expire, e.g. for sessions. It is a shorthand for IdleTimeout
+	// equal to LifeWindow, IdleTimeout wins when both are set.
+	SlidingExpiration bool
+	// CleanWindow is an interval in which a background goroutine removes expired entries from all shards,
+	// so they do not wait for the next Set to their shard. Zero disables the background cleanup.
+	CleanWindow time.Duration
+	// Additional time after the life window during which entry is kept but reported as stale by GetWithInfo.
+	// Zero means entries can be evicted as soon as the life window passes.
+	EvictionGrace time.Duration
+	// PromoteAfterAccesses enables two tiers of entries. Entry read this many times is promoted from the hot tier
+	// with LifeWindow to the warm tier with WarmLifeWindow. Access counter is kept in the entry header and stops
+	// at max uint32 instead of overflowing. Reads take the shard write lock to update it. Zero disables tiers.
+	PromoteAfterAccesses int
+	// TrackAccessCount counts reads of every entry in its header, the count is reported by GetWithInfo.
+	// Reads take the shard write lock to update it.
+	TrackAccessCount bool
+	// WarmLifeWindow is a life window of entries promoted to the warm tier, twice the LifeWindow by default.
+	WarmLifeWindow time.Duration
 	// Max number of entries in life window. Used to allocate proper size of cache in every shard.
 	// When proper value is set then cache will not allocate additional memory
 	MaxEntriesInWindow int
 	// Max size of entry in bytes. Used to allocate proper size of cache in every shard.
 	MaxEntrySize int
+	// CompressAbove enables compression of values which are at least this many bytes long.
+	// Value is stored compressed only when compression makes it smaller. Zero disables compression,
+	// unless Compressor is set, which compresses values of at least 1 KiB.
+	// Values are compressed before the shard lock is taken and decompressed after it is released,
+	// except by Update, Append and Increment which compute the new value from the current one under the lock.
+	CompressAbove int
+	// Compressor used for values selected by CompressAbove, FlateCompressor by default.
+	// Setting it enables compression even when CompressAbove is not set, see CompressAbove.
+	// Package compressors provides faster Snappy and Zstd compressors.
+	Compressor Compressor
+	// BloomFilterBits enables bloom filter of given size in bits in every shard. Get and Has consult it
+	// before taking the shard lock, so misses for keys which were never set are answered without locking.
+	// Deleted keys stay in the filter until Clear. Zero disables the filter.
+	BloomFilterBits int
+	// SnapshotCodec is used by Save and Load to write and read entries, BinarySnapshotCodec by default.
+	SnapshotCodec SnapshotCodec
+	// HardMaxCacheSize is a limit for cache size in MB. Cache will not allocate more memory than this limit,
+	// oldest entries are evicted to make room for new ones instead. Zero means no limit.
+	HardMaxCacheSize int
+	// AdaptiveTTL shortens the life window of entries in shards which are filled above half of their
+	// share of HardMaxCacheSize. The window shrinks linearly with utilization, down to zero for a full shard,
+	// so memory stays bounded while entries are kept as long as possible when there is room.
+	// It has no effect without HardMaxCacheSize.
+	AdaptiveTTL bool
+	// MaxEntryShardShare rejects entries which take more than this fraction of the max shard size,
+	// i.e. HardMaxCacheSize divided by the number of shards, with ErrEntryExceedsShardShare.
+	// For example 0.1 keeps a single entry from taking more than a tenth of its shard.
+	// Entry size includes key, metadata and headers. Zero or no HardMaxCacheSize disables the limit.
+	MaxEntryShardShare float64
+	// MaxEntrySizeHard rejects entries bigger than this many bytes with error matching ErrEntryTooLarge, so a single oversized
+	// entry cannot make the shard queue grow. Unlike MaxEntrySize, which only sizes initial allocation, it is enforced.
+	// Entry size includes key, metadata and headers and counts compressed value. Zero disables the limit.
+	MaxEntrySizeHard int
+	// SampleHotKeys samples one in this many Gets, including misses, to track the most frequently read keys
+	// reported by HotKeys. Tracking is bounded to a few dozen keys and is reset by Clear. Zero disables sampling.
+	SampleHotKeys int
 	// Verbose mode prints information about new memory allocation
 	Verbose bool
 	// Hasher used to map between string keys and unsigned 64bit integers, by default fnv64 hashing is used.
+	// NewXXHasher provides faster hashing of long keys.
 	Hasher Hasher
+	// MaxProbeLength limits number of entries with keys of the same hash chained after the first one, 8 by default and at most 15.
+	// It bounds the number of slots a lookup probes, e.g. when keys are chosen to collide. What happens when the whole chain
+	// is taken is decided by OnChainFull. It has no effect with HashOnly KeyStorage, which cannot tell keys of the same hash apart.
+	MaxProbeLength int
+	// OnChainFull decides what Set of a new key does when the collision chain of its hash is full. EvictOldestInChain is used by default.
+	OnChainFull ChainFullPolicy
+	// VerifyChecksums stores checksum of every entry on Set and verifies it on Get.
+	// Get returns error matching ErrCorrupted when stored bytes do not match the checksum.
+	// It adds CPU cost to every operation, so it is disabled by default.
+	VerifyChecksums bool
+	// OnCorruption decides what happens when Get detects a corrupted entry, i.e. a checksum mismatch
+	// or an index pointing outside of the shard queue. LogAndMiss is used by default.
+	OnCorruption CorruptionPolicy
+	// CorruptionHandler is called with the key and the error when OnCorruption is set to Callback.
+	// It runs under the shard lock, so it must be fast and must not use the cache.
+	CorruptionHandler func(key string, err error)
+	// BeforeSet is called at the beginning of Set, before the shard lock is taken.
+	// Returned bytes are stored instead of the original value, returned error aborts the Set and is returned from it.
+	BeforeSet func(key string, value []byte) ([]byte, error)
+	// OnShardGrow is called when queue of the shard with given index allocates additional memory.
+	// It runs under the shard lock while entry is being pushed, so it must be fast and must not use the cache.
+	OnShardGrow func(shardIndex int, newCapacity int)
+	// OnReallocation is called when queue of the shard with given index allocates additional memory.
+	// It receives capacities before and after the allocation and time it took, e.g. to throttle writes.
+	// Like OnShardGrow it runs under the shard lock.
+	OnReallocation func(shardIndex, oldCapacity, newCapacity int, duration time.Duration)
+	// OnRemoveBatch is called once per shard by bulk eviction, i.e. EvictToSize and CleanWindow cleanup, with copies of all entries
+	// removed from the shard, so they can be written to an external store at once. It is called after
+	// all shard locks are released. Entries reported in a batch are not reported by per-entry callbacks.
+	OnRemoveBatch func(entries []RemovedEntry)
+	// OnRemoveWithReason is called for every live entry removed from the cache, with its key, value and reason.
+	// It runs under the shard lock, so it must be fast and must not use the cache. The value is valid only
+	// during the call and must be copied to be kept. Clear does not report removed entries.
+	OnRemoveWithReason func(key string, entry []byte, reason RemoveReason)
+	// OnEmpty is called when the last entry is removed from the cache.
+	// OnFirstEntry is called when an entry is added to the empty cache.
+	// Each is called once per transition after shard locks are released.
+	OnEmpty      func()
+	OnFirstEntry func()
+	// CaseInsensitiveKeys converts keys to lower case (strings.ToLower) before they are hashed and stored,
+	// so keys differing only in case refer to the same entry.
+	CaseInsensitiveKeys bool
+	// KeyNormalizer converts keys to canonical form, e.g. trims whitespace, before they are hashed and stored.
+	// It is applied by every operation taking a key, before CaseInsensitiveKeys lower casing, so it must be
+	// deterministic. It runs on the hot path of every Get and Set, so it should be cheap and avoid allocations
+	// when key is already canonical.
+	KeyNormalizer func(key string) string
+	// KeyStorage decides what is stored in every entry to tell apart keys with the same hash, FullKey by default.
+	KeyStorage KeyStorage
+	// RejectEmptyKeys makes operations taking a key return ErrEmptyKey for an empty key.
+	RejectEmptyKeys bool
+	// MaxEntriesPerShard limits number of entries kept in every shard. When the limit is reached
+	// entries are evicted according to EvictionPolicy. Zero means no limit.
+	MaxEntriesPerShard int
+	// EvictionPolicy decides which entries are evicted when MaxEntriesPerShard is reached or the shard runs out of memory.
+	EvictionPolicy EvictionPolicy
+	// OverwriteInPlace makes Set of an existing key write the new entry over the previous one when both take
+	// the same number of bytes, e.g. counters or fixed size records, instead of appending it to the shard queue
+	// and leaving the previous one as garbage until it is evicted. Overwritten entry keeps its place in the queue,
+	// so it is evicted for space together with entries set around the time of its first set, and while it is
+	// at the head of the queue it delays cleanup of expired entries set after it. It has no effect under LRU policy,
+	// which moves overwritten entries to the back of the queue.
+	OverwriteInPlace bool
+	// CompactDeadRatio compacts a shard, like CompactShard, after Set or Delete leaves deleted and overwritten entries
+	// taking more than this fraction of bytes used by the shard queue. Compaction copies all live entries
+	// of the shard under its write lock. Zero disables automatic compaction.
+	CompactDeadRatio float64
+	// ShrinkEnabled releases memory of shard queues which grew during a traffic spike. Once a queue grown beyond
+	// its initial size uses less than a quarter of its capacity for a thousand consecutive pushes and pops,
+	// its live entries are compacted into a queue of the initial size by the next Set, Delete or cleanup of the shard.
+	ShrinkEnabled bool
+	// Store is a backing store updated by Set, SetWithTTL, SetMulti and Delete, so the cache can front
+	// a database without every caller writing to both. Evictions and expirations do not reach the store.
+	Store Store
+	// StoreMode decides whether Store is written synchronously or on a background goroutine, WriteThrough by default.
+	StoreMode StoreMode
+	// WriteBehindQueueSize is a number of writes buffered for Store in WriteBehind mode, 1024 by default.
+	// Writes block while the queue is full.
+	WriteBehindQueueSize int
+	// OnStoreError is called with the key and the error when Store fails in WriteBehind mode.
+	// It runs on the write-behind goroutine.
+	OnStoreError func(key string, err error)
+	// Loader loads value and ttl for the key from the backing store, used by RefreshAhead.
+	// Zero ttl stores the value with the life window of the cache, like SetWithTTL.
+	Loader func(key string) ([]byte, time.Duration, error)
+	// RefreshAhead reloads entry with Loader on a background goroutine when it is read with less than this fraction
+	// of its life window left, so hot keys are refreshed before they expire in front of readers.
+	// Only one load of a key runs at a time, shared with GetOrLoad. Loader errors are ignored, the entry expires then.
+	// Zero disables refreshing.
+	RefreshAhead float64
 }
 
+// CorruptionPolicy determines how corrupted entries are handled
+type CorruptionPolicy int
+
+const (
+	// LogAndMiss logs the corruption and reports entry as missing with error matching ErrCorrupted
+	LogAndMiss CorruptionPolicy = iota
+	// Panic panics with error matching ErrCorrupted, so the failure is loud and comes with a stack trace
+	Panic
+	// Callback passes the corruption to Config.CorruptionHandler and reports entry as missing
+	Callback
+)
+
+// ChainFullPolicy determines what happens when a new key collides with keys filling the whole collision chain
+type ChainFullPolicy int
+
+const (
+	// EvictOldestInChain evicts the oldest entry of the chain to make room for the new one
+	EvictOldestInChain ChainFullPolicy = iota
+	// RejectWhenChainFull keeps the chain and returns ErrChainFull from Set
+	RejectWhenChainFull
+)
+
+// EvictionPolicy determines which entries are evicted first
+type EvictionPolicy int
+
+const (
+	// FIFO evicts the least recently set entries first
+	FIFO EvictionPolicy = iota
+	// LRU evicts the least recently read or set entries first. Every read moves the entry to the end of its shard queue,
+	// which copies the entry and takes the shard write lock on reads. Life window is still counted from Set.
+	LRU
+	// TinyLFU keeps frequently accessed entries when the shard is full. Reads and sets of every key are counted
+	// in a small frequency sketch per shard, and a new key is stored only if it was accessed more often recently
+	// than the oldest entry it would evict, otherwise Set returns ErrEntryNotAdmitted. Counts are halved periodically,
+	// so keys which stopped being popular are replaced. Reads take the shard write lock to update the sketch.
+	TinyLFU
+)
+
+// StoreMode determines when Config.Store is written
+type StoreMode int
+
+const (
+	// WriteThrough writes to the store before the cache and returns errors of the store. Entries the store rejects
+	// are not cached, so the cache never holds an entry the store does not have, but an entry the store accepted
+	// is missing from the cache when the cache rejects it afterwards.
+	WriteThrough StoreMode = iota
+	// WriteBehind queues writes to the store on a background goroutine and updates the cache immediately.
+	// Readers may see entries before the store has them, and entries the store fails to write stay cached.
+	// Errors of the store are reported to Config.OnStoreError. Close waits until queued writes are done.
+	WriteBehind
+)
+
+// RemoveReason tells why an entry was removed from the cache
+type RemoveReason int
+
+const (
+	// Expired means entry outlived its life window and was removed by Set or cleanup
+	Expired RemoveReason = iota
+	// NoSpace means entry was evicted to make room for other entries
+	NoSpace
+	// Deleted means entry was removed explicitly, e.g. by Delete
+	Deleted
+	// Replaced means entry was overwritten by a new entry for the same hash
+	Replaced
+)
+
 // DefaultConfig initializes config with default values.
 // When load for BigCache can be predicted in advance then it is better to use custom config.
 func DefaultConfig(eviction time.Duration) Config {
@@ -32,3 +254,49 @@ func DefaultConfig(eviction time.Duration) Config {
 		Hasher:             newDefaultHasher(),
 	}
 }
+
+// Validate checks that all config values are in sane ranges and consistent with each other.
+// It returns a single error matching ErrInvalidConfig which lists every problem found, so all of them can be fixed at once.
+// NewBigCache calls it and refuses configs which do not pass.
+func (c Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Shards > 0 && isPowerOfTwo(c.Shards), "Shards number must be power of two, got %d", c.Shards)
+	check(c.MaxEntrySize > 0, "MaxEntrySize must be positive, got %d", c.MaxEntrySize)
+	check(c.MaxEntriesInWindow > 0, "MaxEntriesInWindow must be positive, got %d", c.MaxEntriesInWindow)
+	check(c.LifeWindow >= 0, "LifeWindow must not be negative, got %s", c.LifeWindow)
+	check(c.IdleTimeout >= 0, "IdleTimeout must not be negative, got %s", c.IdleTimeout)
+	check(!c.SlidingExpiration || c.LifeWindow > 0 || c.IdleTimeout > 0, "SlidingExpiration needs positive LifeWindow")
+	check(c.CleanWindow >= 0, "CleanWindow must not be negative, got %s", c.CleanWindow)
+	check(c.EvictionGrace >= 0, "EvictionGrace must not be negative, got %s", c.EvictionGrace)
+	check(c.WarmLifeWindow >= 0, "WarmLifeWindow must not be negative, got %s", c.WarmLifeWindow)
+	check(c.PromoteAfterAccesses >= 0, "PromoteAfterAccesses must not be negative, got %d", c.PromoteAfterAccesses)
+	check(c.CompressAbove >= 0, "CompressAbove must not be negative, got %d", c.CompressAbove)
+	check(c.BloomFilterBits >= 0, "BloomFilterBits must not be negative, got %d", c.BloomFilterBits)
+	check(c.HardMaxCacheSize >= 0, "HardMaxCacheSize must not be negative, got %d", c.HardMaxCacheSize)
+	check(c.MaxEntryShardShare >= 0 && c.MaxEntryShardShare <= 1, "MaxEntryShardShare must be between 0 and 1, got %g", c.MaxEntryShardShare)
+	check(c.MaxEntrySizeHard >= 0, "MaxEntrySizeHard must not be negative, got %d", c.MaxEntrySizeHard)
+	check(c.CompactDeadRatio >= 0 && c.CompactDeadRatio <= 1, "CompactDeadRatio must be between 0 and 1, got %g", c.CompactDeadRatio)
+	check(c.RefreshAhead >= 0 && c.RefreshAhead <= 1, "RefreshAhead must be between 0 and 1, got %g", c.RefreshAhead)
+	check(c.RefreshAhead == 0 || c.Loader != nil, "Loader must be set when RefreshAhead is set")
+	check(c.SampleHotKeys >= 0, "SampleHotKeys must not be negative, got %d", c.SampleHotKeys)
+	check(c.MaxEntriesPerShard >= 0, "MaxEntriesPerShard must not be negative, got %d", c.MaxEntriesPerShard)
+	check(c.MaxProbeLength >= 0 && c.MaxProbeLength <= maxProbeLength, "MaxProbeLength must be between 0 and %d, got %d", maxProbeLength, c.MaxProbeLength)
+	check(c.OnChainFull >= EvictOldestInChain && c.OnChainFull <= RejectWhenChainFull, "Unknown OnChainFull policy %d", c.OnChainFull)
+	check(c.OnCorruption >= LogAndMiss && c.OnCorruption <= Callback, "Unknown OnCorruption policy %d", c.OnCorruption)
+	check(c.OnCorruption != Callback || c.CorruptionHandler != nil, "CorruptionHandler must be set when OnCorruption is Callback")
+	check(c.KeyStorage >= FullKey && c.KeyStorage <= HashOnly, "Unknown KeyStorage %d", c.KeyStorage)
+	check(c.EvictionPolicy >= FIFO && c.EvictionPolicy <= TinyLFU, "Unknown EvictionPolicy %d", c.EvictionPolicy)
+	check(c.StoreMode >= WriteThrough && c.StoreMode <= WriteBehind, "Unknown StoreMode %d", c.StoreMode)
+	check(c.WriteBehindQueueSize >= 0, "WriteBehindQueueSize must not be negative, got %d", c.WriteBehindQueueSize)
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidConfig, strings.Join(problems, "; "))
+	}
+	return nil
+}
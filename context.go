@@ -0,0 +1,59 @@
+package bigcache
+
+import "context"
+
+// GetContext reads entry for the key like Get, unless ctx is already done, in which case ctx.Err() is returned.
+// Reads do not block on anything but the shard lock, which cannot be interrupted, so ctx is checked before the read.
+func (c *BigCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.Get(key)
+}
+
+// SetContext saves entry under the key like Set, unless ctx is already done, in which case ctx.Err() is returned
+// and nothing is stored.
+func (c *BigCache) SetContext(ctx context.Context, key string, entry []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return c.Set(key, entry)
+}
+
+// GetMultiContext reads copies of entries for the keys like GetMulti. Keys are read in groups per shard and ctx
+// is checked before every group. When ctx is done, the keys read so far are returned together with ctx.Err().
+func (c *BigCache) GetMultiContext(ctx context.Context, keys []string) (map[string][]byte, error) {
+	groups := c.groupByShard(keys)
+	values := make(map[string][]byte, len(keys))
+	for shardIndex, shard := range c.shards {
+		group, ok := groups[shardIndex]
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return values, err
+		}
+		c.readGroup(shard, group, values)
+	}
+	return values, nil
+}
+
+// SetMultiContext saves all entries like SetMulti, checking ctx before entries of every shard are set.
+// When ctx is done, ctx.Err() is returned and entries of the remaining shards are not stored.
+func (c *BigCache) SetMultiContext(ctx context.Context, entries map[string][]byte) error {
+	groups, firstErr := c.groupEntriesByShard(entries)
+	defer c.notifyTransitions()
+	for shardIndex, shard := range c.shards {
+		group, ok := groups[shardIndex]
+		if !ok {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := c.setGroup(shard, group); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
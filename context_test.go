@@ -0,0 +1,89 @@
+package bigcache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextVariants(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	ctx := context.Background()
+
+	// when
+	setErr := cache.SetContext(ctx, "key", []byte("value"))
+	value, getErr := cache.GetContext(ctx, "key")
+	multiSetErr := cache.SetMultiContext(ctx, map[string][]byte{"other": []byte("other value")})
+	values, multiGetErr := cache.GetMultiContext(ctx, []string{"key", "other"})
+
+	// then
+	assert.NoError(t, setErr)
+	assert.NoError(t, getErr)
+	assert.NoError(t, multiSetErr)
+	assert.NoError(t, multiGetErr)
+	assert.Equal(t, []byte("value"), value)
+	assert.Equal(t, map[string][]byte{"key": []byte("value"), "other": []byte("other value")}, values)
+}
+
+func TestContextVariantsWithCancelledContext(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	setErr := cache.SetContext(ctx, "other", []byte("value"))
+	_, getErr := cache.GetContext(ctx, "key")
+	multiSetErr := cache.SetMultiContext(ctx, map[string][]byte{"other": []byte("value")})
+	values, multiGetErr := cache.GetMultiContext(ctx, []string{"key"})
+
+	// then
+	assert.Equal(t, context.Canceled, setErr)
+	assert.Equal(t, context.Canceled, getErr)
+	assert.Equal(t, context.Canceled, multiSetErr)
+	assert.Equal(t, context.Canceled, multiGetErr)
+	assert.Empty(t, values)
+	assert.False(t, cache.Has("other"))
+}
+
+func TestSetMultiContextWithCancelledContextSkipsStore(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := newMapStore()
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Store:              store,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// when
+	err := cache.SetMultiContext(ctx, map[string][]byte{"key": []byte("value"), "other": []byte("value")})
+
+	// then
+	assert.Equal(t, context.Canceled, err)
+	assert.Empty(t, store.entries)
+	assert.False(t, cache.Has("key"))
+}
@@ -0,0 +1,28 @@
+package bigcache
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrCorrupted is matched by errors returned when stored entry does not match its checksum
+var ErrCorrupted = errors.New("Entry corrupted")
+
+// CorruptedEntryError is an error type struct which is returned when entry checksum verification fails
+type CorruptedEntryError struct {
+	Key string
+}
+
+func corrupted(key string) error {
+	return &CorruptedEntryError{key}
+}
+
+// Error returned when entry does not match its checksum.
+func (e *CorruptedEntryError) Error() string {
+	return fmt.Sprintf("Entry %q corrupted", e.Key)
+}
+
+// Is reports whether target is ErrCorrupted.
+func (e *CorruptedEntryError) Is(target error) bool {
+	return target == ErrCorrupted
+}
@@ -0,0 +1,53 @@
+package bigcache
+
+import "encoding/binary"
+
+const counterSizeInBytes = 8 // Number of bytes of a counter entry
+
+// Increment adds delta to the counter stored under the key and returns its new value. Missing, expired
+// and negative entries are treated as a counter at zero, so the counter is created on the first call.
+// Counter is read and written under the shard write lock, so concurrent increments are never lost.
+// Counter is kept as an 8 byte little endian int64 value, which is what Get returns for it, and it wraps around on overflow.
+// Entry of another size returns ErrNotCounter. Metadata and priority of the entry are kept.
+// With Config.OverwriteInPlace counters are updated without growing the shard queue.
+func (c *BigCache) Increment(key string, delta int64) (int64, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return 0, err
+	}
+
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	var counter int64
+	var metadata []byte
+	var priority uint8
+	if wrappedEntry, found := c.liveEntry(shard, key, hashedKey); found {
+		value, err := c.readValue(wrappedEntry)
+		if err != nil {
+			return 0, err
+		}
+		if len(value) != counterSizeInBytes {
+			return 0, ErrNotCounter
+		}
+		counter = int64(binary.LittleEndian.Uint64(value))
+		metadata = copyBytes(readMetadataFromEntry(wrappedEntry))
+		priority = readPriorityFromEntry(wrappedEntry)
+	}
+
+	counter += delta
+	value := make([]byte, counterSizeInBytes)
+	binary.LittleEndian.PutUint64(value, uint64(counter))
+	if err := c.set(shard, hashedKey, key, metadata, c.compress(value), priority); err != nil {
+		return 0, err
+	}
+	return counter, nil
+}
+
+// Decrement subtracts delta from the counter stored under the key like Increment
+func (c *BigCache) Decrement(key string, delta int64) (int64, error) {
+	return c.Increment(key, -delta)
+}
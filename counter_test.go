@@ -0,0 +1,61 @@
+package bigcache
+
+import (
+	"encoding/binary"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncrement(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	var wg sync.WaitGroup
+
+	// when
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.Increment("counter", 2)
+		}()
+	}
+	wg.Wait()
+	counter, err := cache.Decrement("counter", 50)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150), counter)
+	value, _ := cache.Get("counter")
+	assert.Equal(t, uint64(150), binary.LittleEndian.Uint64(value))
+}
+
+func TestIncrementRejectsOtherEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	_, err := cache.Increment("key", 1)
+
+	// then
+	assert.Equal(t, ErrNotCounter, err)
+	value, _ := cache.Get("key")
+	assert.Equal(t, []byte("value"), value)
+}
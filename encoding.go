@@ -2,18 +2,52 @@ package bigcache
 
 import (
 	"encoding/binary"
+	"hash/crc32"
 )
 
 const (
-	timestampSizeInBytes = 8                                                       // Number of bytes used for timestamp
-	hashSizeInBytes      = 8                                                       // Number of bytes used for hash
-	keySizeInBytes       = 2                                                       // Number of bytes used for size of entry key
-	headersSizeInBytes   = timestampSizeInBytes + hashSizeInBytes + keySizeInBytes // Number of bytes used for all headers
+	timestampSizeInBytes = 8 // Number of bytes used for timestamp
+	hashSizeInBytes      = 8 // Number of bytes used for hash
+	checksumSizeInBytes  = 4 // Number of bytes used for checksum of key, metadata and entry
+	keySizeInBytes       = 2 // Number of bytes used for size of entry key
+	metadataSizeInBytes  = 2 // Number of bytes used for size of entry metadata
+	flagsSizeInBytes     = 1 // Number of bytes used for entry flags
+	accessesSizeInBytes  = 4 // Number of bytes used for number of entry accesses
+	prioritySizeInBytes  = 1 // Number of bytes used for entry priority
+	versionSizeInBytes   = 4 // Number of bytes used for entry version
+	ttlSizeInBytes       = 4 // Number of bytes used for entry time to live in seconds
+	// Number of bytes used for all headers
+	headersSizeInBytes = timestampSizeInBytes + hashSizeInBytes + checksumSizeInBytes + keySizeInBytes + metadataSizeInBytes +
+		flagsSizeInBytes + accessesSizeInBytes + prioritySizeInBytes + versionSizeInBytes + ttlSizeInBytes
+
+	hashOffset         = timestampSizeInBytes
+	checksumOffset     = hashOffset + hashSizeInBytes
+	keySizeOffset      = checksumOffset + checksumSizeInBytes
+	metadataSizeOffset = keySizeOffset + keySizeInBytes
+	flagsOffset        = metadataSizeOffset + metadataSizeInBytes
+	accessesOffset     = flagsOffset + flagsSizeInBytes
+	priorityOffset     = accessesOffset + accessesSizeInBytes
+	versionOffset      = priorityOffset + prioritySizeInBytes
+	ttlOffset          = versionOffset + versionSizeInBytes
+)
+
+const (
+	flagCompressed byte = 1 << iota // Entry value is compressed
+	flagPromoted                    // Entry was promoted to the warm tier
+	flagHashedKey                   // Entry stores secondary hash of the key instead of the key
+	flagNegative                    // Entry is a tombstone of a key known to be missing
+	// Upper bits of flags keep number of the collision chain slot of the entry, zero for the home slot of its hash
+	probeShift = 4
 )
 
 func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *[]byte) []byte {
+	return wrapEntryWithMetadata(timestamp, hash, key, nil, entry, buffer)
+}
+
+func wrapEntryWithMetadata(timestamp uint64, hash uint64, key string, metadata []byte, entry []byte, buffer *[]byte) []byte {
 	keyLength := len(key)
-	blobLength := len(entry) + headersSizeInBytes + keyLength
+	metadataLength := len(metadata)
+	blobLength := len(entry) + headersSizeInBytes + keyLength + metadataLength
 
 	if blobLength > len(*buffer) {
 		*buffer = make([]byte, blobLength)
@@ -21,32 +55,120 @@ func wrapEntry(timestamp uint64, hash uint64, key string, entry []byte, buffer *
 	blob := *buffer
 
 	binary.LittleEndian.PutUint64(blob, timestamp)
-	binary.LittleEndian.PutUint64(blob[timestampSizeInBytes:], hash)
-	binary.LittleEndian.PutUint16(blob[timestampSizeInBytes+hashSizeInBytes:], uint16(keyLength))
+	binary.LittleEndian.PutUint64(blob[hashOffset:], hash)
+	binary.LittleEndian.PutUint32(blob[checksumOffset:], 0)
+	binary.LittleEndian.PutUint16(blob[keySizeOffset:], uint16(keyLength))
+	binary.LittleEndian.PutUint16(blob[metadataSizeOffset:], uint16(metadataLength))
+	blob[flagsOffset] = 0
+	binary.LittleEndian.PutUint32(blob[accessesOffset:], 0)
+	blob[priorityOffset] = 0
+	binary.LittleEndian.PutUint32(blob[versionOffset:], 0)
+	binary.LittleEndian.PutUint32(blob[ttlOffset:], 0)
 	copy(blob[headersSizeInBytes:], []byte(key))
-	copy(blob[headersSizeInBytes+keyLength:], entry)
+	copy(blob[headersSizeInBytes+keyLength:], metadata)
+	copy(blob[headersSizeInBytes+keyLength+metadataLength:], entry)
 
 	return blob[:blobLength]
 }
 
 func readEntry(data []byte) []byte {
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])
-	return data[headersSizeInBytes+length:]
+	keyLength := binary.LittleEndian.Uint16(data[keySizeOffset:])
+	metadataLength := binary.LittleEndian.Uint16(data[metadataSizeOffset:])
+	return data[headersSizeInBytes+int(keyLength)+int(metadataLength):]
+}
+
+// isFillerBlob reports blob shorter than entry headers. Shard queue leaves a zeroed blob between entries
+// when it grows while wrapped, which is not an entry and may be too short to read header fields from.
+func isFillerBlob(data []byte) bool {
+	return len(data) < headersSizeInBytes
 }
 
 func readTimestampFromEntry(data []byte) uint64 {
 	return binary.LittleEndian.Uint64(data)
 }
 
+func writeTimestampToEntry(data []byte, timestamp uint64) {
+	binary.LittleEndian.PutUint64(data, timestamp)
+}
+
+// readKeyFromEntry returns key of the entry, or empty string when the key itself is not stored
 func readKeyFromEntry(data []byte) string {
-	length := binary.LittleEndian.Uint16(data[timestampSizeInBytes+hashSizeInBytes:])
-	return string(data[headersSizeInBytes : headersSizeInBytes+length])
+	if readFlagsFromEntry(data)&flagHashedKey != 0 {
+		return ""
+	}
+	return readStoredKeyFromEntry(data)
+}
+
+func readStoredKeyFromEntry(data []byte) string {
+	return string(readStoredKeyBytesFromEntry(data))
+}
+
+// readStoredKeyBytesFromEntry returns stored key of the entry without copying it
+func readStoredKeyBytesFromEntry(data []byte) []byte {
+	length := binary.LittleEndian.Uint16(data[keySizeOffset:])
+	return data[headersSizeInBytes : headersSizeInBytes+length]
+}
+
+func readMetadataFromEntry(data []byte) []byte {
+	keyLength := int(binary.LittleEndian.Uint16(data[keySizeOffset:]))
+	metadataLength := int(binary.LittleEndian.Uint16(data[metadataSizeOffset:]))
+	return data[headersSizeInBytes+keyLength : headersSizeInBytes+keyLength+metadataLength]
+}
+
+func readFlagsFromEntry(data []byte) byte {
+	return data[flagsOffset]
+}
+
+func writeFlagsToEntry(data []byte, flags byte) {
+	data[flagsOffset] = flags
 }
 
+func readAccessesFromEntry(data []byte) uint32 {
+	return binary.LittleEndian.Uint32(data[accessesOffset:])
+}
+
+func writeAccessesToEntry(data []byte, accesses uint32) {
+	binary.LittleEndian.PutUint32(data[accessesOffset:], accesses)
+}
+
+func readPriorityFromEntry(data []byte) uint8 {
+	return data[priorityOffset]
+}
+
+func writePriorityToEntry(data []byte, priority uint8) {
+	data[priorityOffset] = priority
+}
+
+func readVersionFromEntry(data []byte) uint32 {
+	return binary.LittleEndian.Uint32(data[versionOffset:])
+}
+
+func writeVersionToEntry(data []byte, version uint32) {
+	binary.LittleEndian.PutUint32(data[versionOffset:], version)
+}
+
+func readTTLFromEntry(data []byte) uint32 {
+	return binary.LittleEndian.Uint32(data[ttlOffset:])
+}
+
+func writeTTLToEntry(data []byte, ttl uint32) {
+	binary.LittleEndian.PutUint32(data[ttlOffset:], ttl)
+}
+
+// readHashFromEntry returns hash the entry is indexed under, which differs from hash of its key for chained entries
 func readHashFromEntry(data []byte) uint64 {
-	return binary.LittleEndian.Uint64(data[timestampSizeInBytes:])
+	return binary.LittleEndian.Uint64(data[hashOffset:])
 }
 
+// resetKeyFromEntry marks the entry as dead, so it is skipped when it is popped from the queue
 func resetKeyFromEntry(data []byte) {
-	binary.LittleEndian.PutUint64(data[timestampSizeInBytes:], 0)
+	binary.LittleEndian.PutUint64(data[hashOffset:], 0)
+}
+
+func writeChecksumToEntry(data []byte) {
+	binary.LittleEndian.PutUint32(data[checksumOffset:], crc32.ChecksumIEEE(data[headersSizeInBytes:]))
+}
+
+func verifyChecksumOfEntry(data []byte) bool {
+	return binary.LittleEndian.Uint32(data[checksumOffset:]) == crc32.ChecksumIEEE(data[headersSizeInBytes:])
 }
@@ -44,3 +44,17 @@ func TestAllocateBiggerBuffer(t *testing.T) {
 	assert.Equal(t, data, readEntry(wrapped))
 	assert.Equal(t, 2+headersSizeInBytes, len(buffer))
 }
+
+func TestChecksum(t *testing.T) {
+	// given
+	buffer := make([]byte, 100)
+	wrapped := wrapEntry(uint64(time.Now().Unix()), 42, "key", []byte("data"), &buffer)
+
+	// when
+	writeChecksumToEntry(wrapped)
+
+	// then
+	assert.True(t, verifyChecksumOfEntry(wrapped))
+	wrapped[len(wrapped)-1] = 'x'
+	assert.False(t, verifyChecksumOfEntry(wrapped))
+}
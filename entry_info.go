@@ -0,0 +1,87 @@
+package bigcache
+
+import "time"
+
+// Share of the life window below which remaining TTL of an entry is reported as expiring soon
+const expiresSoonThreshold = 0.1
+
+// RemovedEntry holds copies of key and value of an entry removed from the cache
+type RemovedEntry struct {
+	Key   string
+	Value []byte
+}
+
+// EntryInfo holds a copy of a cache entry together with its metadata
+type EntryInfo struct {
+	Key   string
+	Value []byte
+	Hash  uint64
+	// Timestamp is the epoch second the entry was set at, moved forward by reads when Config.IdleTimeout is set
+	Timestamp uint64
+	// Accesses is a number of reads of the entry, counted when Config.TrackAccessCount is set
+	Accesses uint32
+	// Stale is set when entry outlived the life window and is kept only because of the eviction grace period
+	Stale bool
+	// TTL is time left until the entry outlives its life window, zero for stale entries
+	TTL time.Duration
+	// ExpiresSoon is set when less than a tenth of the life window of the entry is left, or when it is stale,
+	// e.g. to refresh the entry in the background while still serving it
+	ExpiresSoon bool
+	// Metadata stored with SetWithMetadata, nil when there is none
+	Metadata []byte
+	// Priority of the entry, see SetWithPriority
+	Priority uint8
+	// Version is incremented by every set of the key, see SetIfVersion
+	Version uint32
+	// ExplicitTTL is ttl the entry was set with, e.g. by SetWithTTL or SetNegative, zero when it uses the life window
+	ExplicitTTL time.Duration
+	// Negative is set for tombstones stored with SetNegative
+	Negative bool
+	// Promoted is set for entries promoted to the warm tier, see Config.PromoteAfterAccesses
+	Promoted bool
+}
+
+// setRemainingTTL fills TTL and ExpiresSoon of info from the entry life window.
+// Timestamps have one second precision, so does TTL.
+func (c *BigCache) setRemainingTTL(info *EntryInfo, wrappedEntry []byte) {
+	window := c.entryLifeWindow(wrappedEntry)
+	age := entryAge(uint64(c.clock.Epoch()), readTimestampFromEntry(wrappedEntry))
+	var remaining uint64
+	if age < window {
+		remaining = window - age
+	}
+	info.TTL = time.Duration(remaining) * time.Second
+	info.ExpiresSoon = float64(remaining) < expiresSoonThreshold*float64(window)
+}
+
+// copyEntryInfo returns copy of the entry without its value and copy of the value as stored, shard must be locked.
+// The value is set by withValue once the shard lock is released, so it is decompressed outside of the lock.
+func (c *BigCache) copyEntryInfo(wrappedEntry []byte) (EntryInfo, storedValue) {
+	info := EntryInfo{
+		Key:         readKeyFromEntry(wrappedEntry),
+		Hash:        homeHash(wrappedEntry),
+		Timestamp:   readTimestampFromEntry(wrappedEntry),
+		Accesses:    readAccessesFromEntry(wrappedEntry),
+		Stale:       c.isExpired(wrappedEntry, uint64(c.clock.Epoch())),
+		Priority:    readPriorityFromEntry(wrappedEntry),
+		Version:     readVersionFromEntry(wrappedEntry),
+		ExplicitTTL: time.Duration(readTTLFromEntry(wrappedEntry)) * time.Second,
+		Negative:    isNegative(wrappedEntry),
+		Promoted:    readFlagsFromEntry(wrappedEntry)&flagPromoted != 0,
+	}
+	if metadata := readMetadataFromEntry(wrappedEntry); len(metadata) > 0 {
+		info.Metadata = copyBytes(metadata)
+	}
+	c.setRemainingTTL(&info, wrappedEntry)
+	return info, copyStoredValue(wrappedEntry)
+}
+
+// withValue returns info with value copied by copyEntryInfo, decompressed when it was stored compressed
+func (c *BigCache) withValue(info EntryInfo, value storedValue) (EntryInfo, error) {
+	decompressed, err := c.decompress(value)
+	if err != nil {
+		return EntryInfo{}, err
+	}
+	info.Value = decompressed
+	return info, nil
+}
@@ -1,17 +1,51 @@
 package bigcache
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrEntryNotFound is matched by errors returned when there is no entry for provided key
+var ErrEntryNotFound = errors.New("Entry not found")
+
+// ErrEntryExpired is matched by errors returned when entry for provided key outlived its life window
+// and eviction grace period but was not evicted yet
+var ErrEntryExpired = errors.New("Entry expired")
 
 // EntryNotFoundError is an error type struct which is returned when entry was not found for provided key
 type EntryNotFoundError struct {
-	message string
+	Key string
 }
 
 func notFound(key string) error {
-	return &EntryNotFoundError{fmt.Sprintf("Entry %q not found", key)}
+	return &EntryNotFoundError{key}
 }
 
 // Error returned when entry does not exist.
 func (e EntryNotFoundError) Error() string {
-	return e.message
+	return fmt.Sprintf("Entry %q not found", e.Key)
+}
+
+// Is reports whether target is ErrEntryNotFound.
+func (e EntryNotFoundError) Is(target error) bool {
+	return target == ErrEntryNotFound
+}
+
+// EntryExpiredError is an error type struct which is returned when entry for provided key has expired
+type EntryExpiredError struct {
+	Key string
+}
+
+func expired(key string) error {
+	return &EntryExpiredError{key}
+}
+
+// Error returned when entry has expired.
+func (e *EntryExpiredError) Error() string {
+	return fmt.Sprintf("Entry %q expired", e.Key)
+}
+
+// Is reports whether target is ErrEntryExpired.
+func (e *EntryExpiredError) Is(target error) bool {
+	return target == ErrEntryExpired
 }
@@ -0,0 +1,51 @@
+package bigcache
+
+import "errors"
+
+// ErrInvalidConfig is matched by errors returned by Config.Validate and NewBigCache for invalid config
+var ErrInvalidConfig = errors.New("Invalid config")
+
+// ErrCacheClosed is returned by operations on a cache after Close
+var ErrCacheClosed = errors.New("Cache is closed")
+
+// ErrEmptyKey is returned for an empty key when Config.RejectEmptyKeys is set
+var ErrEmptyKey = errors.New("Empty key")
+
+// ErrMetadataTooLarge is returned when serialized entry metadata exceeds MaxMetadataSize
+var ErrMetadataTooLarge = errors.New("Metadata too large")
+
+// ErrCacheEmpty is returned by operations which need at least one entry in the cache
+var ErrCacheEmpty = errors.New("Cache is empty")
+
+// ErrEntryTooLarge is returned when entry does not fit into the shard even after evicting all other entries.
+// Entries bigger than Config.MaxEntrySizeHard are rejected with an error wrapping it, which names the limit.
+var ErrEntryTooLarge = errors.New("Entry is bigger than max shard size")
+
+// ErrShardFull is returned when the shard queue cannot make room for an entry which is within size limits
+// even after evicting all other entries, e.g. because the queue cannot grow to fit its own bookkeeping
+var ErrShardFull = errors.New("Shard is full")
+
+// ErrEntryExceedsShardShare is returned when entry is bigger than Config.MaxEntryShardShare of the max shard size
+var ErrEntryExceedsShardShare = errors.New("Entry is bigger than allowed share of max shard size")
+
+// ErrNegativeCached is returned by reads of a key stored with SetNegative, i.e. known to be missing in the backing store
+var ErrNegativeCached = errors.New("Entry is cached as missing")
+
+// ErrInvalidIteratorState is returned by EntryInfoIterator.Value when the iterator is not positioned at an entry
+var ErrInvalidIteratorState = errors.New("Iterator is in invalid state. Use HasNext to move to next position")
+
+// ErrUnsupportedSnapshot is returned by Load when snapshot was not written by Save or by a supported format version
+var ErrUnsupportedSnapshot = errors.New("Unsupported snapshot format")
+
+// ErrChainFull is returned by Set of a new key when collision chain of its hash is full and Config.OnChainFull is RejectWhenChainFull
+var ErrChainFull = errors.New("Collision chain is full")
+
+// ErrEntryNotAdmitted is returned by Set under TinyLFU policy when the shard is full and the key was accessed
+// less often recently than the entry it would evict, so the new entry is not stored
+var ErrEntryNotAdmitted = errors.New("Entry not admitted")
+
+// ErrKeysNotStored is returned by operations which need keys of entries when Config.KeyStorage does not keep them
+var ErrKeysNotStored = errors.New("Keys are not stored")
+
+// ErrNotCounter is returned by Increment and Decrement when the entry is not an 8 byte counter
+var ErrNotCounter = errors.New("Entry is not a counter")
@@ -0,0 +1,19 @@
+package bigcache
+
+import "expvar"
+
+// expvarStats is published by PublishExpvar
+type expvarStats struct {
+	FullStats
+	// Entries is a number of entries in the cache, see Size
+	Entries uint64 `json:"entries"`
+}
+
+// PublishExpvar publishes statistics of the cache in expvar under name, so they are served on /debug/vars
+// with other variables of the process. Statistics are read on every request. Like expvar.Publish it panics
+// when name is already published, and the cache stays published for the lifetime of the process.
+func (c *BigCache) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return expvarStats{FullStats: c.FullStats(), Entries: c.Size()}
+	}))
+}
@@ -0,0 +1,37 @@
+package bigcache
+
+import (
+	"encoding/json"
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishExpvar(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	cache.Get("key")
+	cache.Get("missing")
+
+	// when
+	cache.PublishExpvar("TestPublishExpvar")
+	var published map[string]interface{}
+	err := json.Unmarshal([]byte(expvar.Get("TestPublishExpvar").String()), &published)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, float64(1), published["hits"])
+	assert.Equal(t, float64(1), published["misses"])
+	assert.Equal(t, float64(1), published["entries"])
+	assert.Panics(t, func() { cache.PublishExpvar("TestPublishExpvar") })
+}
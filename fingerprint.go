@@ -0,0 +1,52 @@
+package bigcache
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Fingerprint returns a hash of cache contents which does not depend on insertion order, sharding or hasher.
+// Caches holding the same keys with the same values have the same fingerprint, so it can be compared
+// to detect diverged replicas. Timestamps are ignored, see FingerprintWithTimestamps.
+// Every entry is hashed under the read lock of its shard, so it takes O(n) time.
+func (c *BigCache) Fingerprint() uint64 {
+	return c.fingerprint(false)
+}
+
+// FingerprintWithTimestamps returns a hash of cache contents like Fingerprint, including entry timestamps
+func (c *BigCache) FingerprintWithTimestamps() uint64 {
+	return c.fingerprint(true)
+}
+
+func (c *BigCache) fingerprint(withTimestamps bool) uint64 {
+	var fingerprint uint64
+	h := fnv.New64a()
+	header := make([]byte, 8)
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		for _, index := range shard.hashmap {
+			wrappedEntry, err := shard.entries.Get(int(index))
+			if err != nil {
+				continue
+			}
+			value, err := c.readValue(wrappedEntry)
+			if err != nil {
+				continue
+			}
+			key := readKeyFromEntry(wrappedEntry)
+
+			h.Reset()
+			binary.LittleEndian.PutUint64(header, uint64(len(key)))
+			h.Write(header)
+			h.Write([]byte(key))
+			h.Write(value)
+			if withTimestamps {
+				binary.LittleEndian.PutUint64(header, readTimestampFromEntry(wrappedEntry))
+				h.Write(header)
+			}
+			fingerprint ^= h.Sum64()
+		}
+		shard.lock.RUnlock()
+	}
+	return fingerprint
+}
@@ -0,0 +1,43 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	replica, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("key", []byte("value"))
+	cache.Set("key2", []byte("value2"))
+	clock.set(1)
+	replica.Set("key2", []byte("value2"))
+	replica.Set("key", []byte("value"))
+	fingerprint := cache.Fingerprint()
+
+	// when
+	replicaFingerprint := replica.Fingerprint()
+	replicaFingerprintWithTimestamps := replica.FingerprintWithTimestamps()
+	replica.Set("key", []byte("other value"))
+
+	// then
+	assert.Equal(t, fingerprint, replicaFingerprint)
+	assert.NotEqual(t, fingerprint, replica.Fingerprint())
+	assert.NotEqual(t, cache.FingerprintWithTimestamps(), replicaFingerprintWithTimestamps)
+}
@@ -0,0 +1,26 @@
+package bigcache
+
+const (
+	// offset64 FNVa offset basis. See https://en.wikipedia.org/wiki/Fowler–Noll–Vo_hash_function#FNV-1a_hash
+	offset64 = 14695981039346656037
+	// prime64 FNVa prime value. See https://en.wikipedia.org/wiki/Fowler–Noll–Vo_hash_function#FNV-1a_hash
+	prime64 = 1099511628211
+)
+
+// NewFNVHasher returns Hasher computing 64 bit FNV-1a hash, the default one
+func NewFNVHasher() Hasher {
+	return fnv64a{}
+}
+
+type fnv64a struct {
+}
+
+// Sum64 gets the string and returns its uint64 hash value. It reads the string in place, so it does not allocate.
+func (f fnv64a) Sum64(key string) uint64 {
+	var hash uint64 = offset64
+	for i := 0; i < len(key); i++ {
+		hash ^= uint64(key[i])
+		hash *= prime64
+	}
+	return hash
+}
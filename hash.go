@@ -1,12 +1,12 @@
 package bigcache
 
-import (
-	"hash/fnv"
-)
-
 // Hasher is responsible for generating unsigned, 64 bit hash of provided string. Hasher should minimize collisions
 // (generating same hash for different strings) and while performance is also important fast functions are preferable (i.e.
 // you can use FarmHash family).
+//
+// Custom hasher is injected with Config.Hasher, FNV-1a provided by NewFNVHasher is used when it is nil.
+// NewXXHasher provides xxHash64, which is faster for long keys. Hasher must be safe for concurrent use
+// and must return the same hash for the same key for the whole life of the cache, see Rehash for changing it.
 type Hasher interface {
 	Sum64(string) uint64
 }
@@ -14,12 +14,3 @@ type Hasher interface {
 func newDefaultHasher() Hasher {
 	return fnv64a{}
 }
-
-type fnv64a struct {
-}
-
-func (f fnv64a) Sum64(key string) uint64 {
-	h := fnv.New64a()
-	h.Write([]byte(key))
-	return h.Sum64()
-}
@@ -1,7 +1,62 @@
 package bigcache
 
+import (
+	"hash/fnv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
 type hashStub uint64
 
 func (stub hashStub) Sum64(_ string) uint64 {
 	return uint64(stub)
 }
+
+func TestFNVHasherMatchesStandardLibrary(t *testing.T) {
+	t.Parallel()
+
+	for _, key := range []string{"", "a", "key", "Nobody inspects the spammish repetition"} {
+		// given
+		expected := fnv.New64a()
+		expected.Write([]byte(key))
+
+		// then
+		assert.Equal(t, expected.Sum64(), NewFNVHasher().Sum64(key))
+	}
+}
+
+func TestXXHasher(t *testing.T) {
+	t.Parallel()
+
+	// given
+	hasher := NewXXHasher()
+
+	// then
+	assert.Equal(t, uint64(0xef46db3751d8e999), hasher.Sum64(""))
+	assert.Equal(t, uint64(0xd24ec4f1a98c6e5b), hasher.Sum64("a"))
+	assert.Equal(t, uint64(0x44bc2cf5ad770999), hasher.Sum64("abc"))
+	assert.Equal(t, uint64(0xfbcea83c8a378bf1), hasher.Sum64("Nobody inspects the spammish repetition"))
+}
+
+func TestCacheWithXXHasher(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             NewXXHasher(),
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+	cachedValue, err := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+}
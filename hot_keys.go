@@ -0,0 +1,87 @@
+package bigcache
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+const hotKeysCapacity = 32 // Number of keys tracked by hot keys sketch
+
+// hotKeys keeps approximate top-K of sampled keys using space saving algorithm. When the sketch is full,
+// a new key replaces the least counted one and inherits its count, so memory stays bounded
+// while frequently sampled keys stay in the sketch.
+type hotKeys struct {
+	every   uint64
+	samples uint64
+	lock    sync.Mutex
+	counts  map[string]uint64
+}
+
+func newHotKeys(every int) *hotKeys {
+	return &hotKeys{
+		every:  uint64(every),
+		counts: make(map[string]uint64, hotKeysCapacity),
+	}
+}
+
+// sample records the key once every configured number of calls
+func (h *hotKeys) sample(key string) {
+	if atomic.AddUint64(&h.samples, 1)%h.every != 0 {
+		return
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _, ok := h.counts[key]; ok {
+		h.counts[key]++
+		return
+	}
+	// key may share memory with bytes of the caller, see GetBytesKey
+	key = strings.Clone(key)
+	if len(h.counts) < hotKeysCapacity {
+		h.counts[key] = 1
+		return
+	}
+	var minKey string
+	var minCount uint64
+	for k, count := range h.counts {
+		if minKey == "" || count < minCount {
+			minKey, minCount = k, count
+		}
+	}
+	delete(h.counts, minKey)
+	h.counts[key] = minCount + 1
+}
+
+// top returns tracked keys from the most to the least sampled one
+func (h *hotKeys) top() []string {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	keys := make([]string, 0, len(h.counts))
+	for key := range h.counts {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if h.counts[keys[i]] != h.counts[keys[j]] {
+			return h.counts[keys[i]] > h.counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func (h *hotKeys) reset() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.counts = make(map[string]uint64, hotKeysCapacity)
+}
+
+// HotKeys returns the most frequently read keys, from the hottest one, as sampled by Get when Config.SampleHotKeys is set.
+// Counts are approximate and at most a few dozen keys are reported. It returns nil when sampling is disabled.
+func (c *BigCache) HotKeys() []string {
+	if c.hotKeys == nil {
+		return nil
+	}
+	return c.hotKeys.top()
+}
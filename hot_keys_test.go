@@ -0,0 +1,67 @@
+package bigcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotKeysSketchIsBounded(t *testing.T) {
+	t.Parallel()
+
+	// given
+	sketch := newHotKeys(1)
+
+	// when
+	for i := 0; i < 1000; i++ {
+		sketch.sample("hot")
+		sketch.sample(fmt.Sprintf("cold-%d", i))
+	}
+
+	// then
+	top := sketch.top()
+	assert.Len(t, top, hotKeysCapacity)
+	assert.Equal(t, "hot", top[0])
+}
+
+func TestHotKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		SampleHotKeys:      3,
+	})
+	cache.Set("hot", []byte("value"))
+
+	// when
+	for i := 0; i < 100; i++ {
+		cache.Get("hot")
+		cache.Get("hot")
+		cache.Get("warm")
+		cache.Get(fmt.Sprintf("cold-%d", i))
+	}
+
+	// then
+	assert.Equal(t, []string{"hot", "warm"}, cache.HotKeys()[:2])
+	cache.Clear()
+	assert.Empty(t, cache.HotKeys())
+}
+
+func TestHotKeysDisabled(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(DefaultConfig(5 * time.Second))
+
+	// when
+	cache.Get("key")
+
+	// then
+	assert.Nil(t, cache.HotKeys())
+}
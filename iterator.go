@@ -0,0 +1,82 @@
+package bigcache
+
+// EntryInfoIterator walks over a snapshot of keys of all shards and reads entries one at a time.
+// Keys of a shard are copied under its read lock when the iterator reaches the shard, and every entry is copied
+// under the read lock when the iterator moves to it, so the cache can be used and modified meanwhile.
+// Entries removed after their keys were copied are skipped, entries added meanwhile may be missed.
+// The iterator itself is not safe for concurrent use.
+type EntryInfoIterator struct {
+	cache      *BigCache
+	shardIndex int
+	hashedKeys []uint64
+	keyIndex   int
+	current    EntryInfo
+	valid      bool
+}
+
+// Iterator returns iterator positioned before the first entry of the cache
+func (c *BigCache) Iterator() *EntryInfoIterator {
+	return &EntryInfoIterator{cache: c}
+}
+
+// HasNext moves the iterator to the next entry and reports whether there is one
+func (it *EntryInfoIterator) HasNext() bool {
+	it.valid = false
+	for {
+		for it.keyIndex < len(it.hashedKeys) {
+			hashedKey := it.hashedKeys[it.keyIndex]
+			it.keyIndex++
+			if info, ok := it.cache.copyEntry(it.cache.shards[it.shardIndex-1], hashedKey); ok {
+				it.current, it.valid = info, true
+				return true
+			}
+		}
+		if it.shardIndex >= len(it.cache.shards) {
+			return false
+		}
+		it.hashedKeys = it.cache.shards[it.shardIndex].copyHashedKeys()
+		it.keyIndex = 0
+		it.shardIndex++
+	}
+}
+
+// Value returns copy of the entry the iterator is positioned at.
+// It returns ErrInvalidIteratorState when HasNext was not called or returned false.
+func (it *EntryInfoIterator) Value() (EntryInfo, error) {
+	if !it.valid {
+		return EntryInfo{}, ErrInvalidIteratorState
+	}
+	return it.current, nil
+}
+
+// copyHashedKeys returns hashed keys of all entries in the shard
+func (s *cacheShard) copyHashedKeys() []uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	hashedKeys := make([]uint64, 0, len(s.hashmap))
+	for hashedKey := range s.hashmap {
+		hashedKeys = append(hashedKeys, hashedKey)
+	}
+	return hashedKeys
+}
+
+// copyEntry returns copy of the entry for hashed key, or false when there is no such entry
+func (c *BigCache) copyEntry(shard *cacheShard, hashedKey uint64) (EntryInfo, bool) {
+	shard.lock.RLock()
+	itemIndex := shard.hashmap[hashedKey]
+	if itemIndex == 0 {
+		shard.lock.RUnlock()
+		return EntryInfo{}, false
+	}
+	wrappedEntry, err := shard.entries.Get(int(itemIndex))
+	if err != nil {
+		shard.lock.RUnlock()
+		return EntryInfo{}, false
+	}
+	info, value := c.copyEntryInfo(wrappedEntry)
+	shard.lock.RUnlock()
+
+	info, err = c.withValue(info, value)
+	return info, err == nil
+}
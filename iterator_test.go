@@ -0,0 +1,134 @@
+package bigcache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             8,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntrySize:       256,
+	})
+	for i := 0; i < 50; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	iterator := cache.Iterator()
+	_, beforeErr := iterator.Value()
+
+	// when
+	values := make(map[string]string)
+	for iterator.HasNext() {
+		entry, err := iterator.Value()
+		assert.NoError(t, err)
+		values[entry.Key] = string(entry.Value)
+	}
+
+	// then
+	assert.Equal(t, ErrInvalidIteratorState, beforeErr)
+	assert.Len(t, values, 50)
+	assert.Equal(t, "value-7", values["key-7"])
+	_, afterErr := iterator.Value()
+	assert.Equal(t, ErrInvalidIteratorState, afterErr)
+}
+
+func TestIteratorReportsTimestampAndTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         20 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	clock.set(2)
+	cache.Set("key", []byte("value"))
+	clock.set(21)
+
+	// when
+	iterator := cache.Iterator()
+	iterator.HasNext()
+	entry, _ := iterator.Value()
+	var iterated []EntryInfo
+	cache.IterateWithInfo(func(info EntryInfo) {
+		iterated = append(iterated, info)
+	})
+
+	// then
+	assert.Equal(t, uint64(2), entry.Timestamp)
+	assert.Equal(t, time.Second, entry.TTL)
+	assert.True(t, entry.ExpiresSoon)
+	assert.Equal(t, []EntryInfo{entry}, iterated)
+}
+
+func TestIteratorSkipsDeletedEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("first", []byte("value"))
+	cache.Set("second", []byte("value"))
+	iterator := cache.Iterator()
+	iterator.HasNext()
+	entry, _ := iterator.Value()
+
+	// when
+	if entry.Key == "first" {
+		cache.Delete("second")
+	} else {
+		cache.Delete("first")
+	}
+
+	// then
+	assert.False(t, iterator.HasNext())
+}
+
+func TestIteratorDuringConcurrentWrites(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 100,
+		MaxEntrySize:       256,
+	})
+	for i := 0; i < 100; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), []byte("value"))
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			cache.Set(fmt.Sprintf("key-%d", i%200), []byte("updated"))
+		}
+	}()
+
+	// when
+	iterator := cache.Iterator()
+	for iterator.HasNext() {
+		entry, err := iterator.Value()
+
+		// then
+		assert.NoError(t, err)
+		assert.NotEmpty(t, entry.Value)
+	}
+	wg.Wait()
+}
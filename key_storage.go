@@ -0,0 +1,47 @@
+package bigcache
+
+import (
+	"encoding/binary"
+	"hash/crc64"
+)
+
+// KeyStorage determines what is stored in entries to compare keys which have the same hash
+type KeyStorage int
+
+const (
+	// FullKey stores the whole key, so keys with the same hash are always told apart
+	FullKey KeyStorage = iota
+	// SecondaryHashKey stores 8 byte CRC-64 of the key instead of the key, which saves memory for long keys.
+	// Two keys are confused only when both their hashes collide, with probability around 2^-64 for a pair of keys
+	// which already share the primary hash. Operations reporting keys, e.g. iteration, report empty keys,
+	// Save and Rehash return ErrKeysNotStored.
+	SecondaryHashKey
+	// HashOnly stores nothing, so keys with the same hash refer to the same entry. It has the limitations
+	// of SecondaryHashKey and the collision probability of the hasher alone.
+	HashOnly
+)
+
+var secondaryHashTable = crc64.MakeTable(crc64.ECMA)
+
+// storedKey returns what is stored in the entry instead of the key and flags marking it
+func (c *BigCache) storedKey(key string) (string, byte) {
+	switch c.config.KeyStorage {
+	case SecondaryHashKey:
+		hashed := make([]byte, 8)
+		binary.LittleEndian.PutUint64(hashed, crc64.Checksum([]byte(key), secondaryHashTable))
+		return string(hashed), flagHashedKey
+	case HashOnly:
+		return "", 0
+	default:
+		return key, 0
+	}
+}
+
+// keyMatches checks whether entry was stored for the key
+func (c *BigCache) keyMatches(wrappedEntry []byte, key string) bool {
+	if c.config.KeyStorage == HashOnly {
+		return true
+	}
+	storedKey, _ := c.storedKey(key)
+	return readStoredKeyFromEntry(wrappedEntry) == storedKey
+}
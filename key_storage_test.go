@@ -0,0 +1,62 @@
+package bigcache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecondaryHashKeyStorage(t *testing.T) {
+	t.Parallel()
+
+	// given
+	longKey := strings.Repeat("k", 300)
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hashStub(5),
+		KeyStorage:         SecondaryHashKey,
+	})
+	cache.Set(longKey, []byte("value"))
+
+	// when
+	cachedValue, err := cache.Get(longKey)
+	_, collisionErr := cache.Get("other")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+	assert.Error(t, collisionErr)
+	key, size := cache.LargestEntry()
+	assert.Equal(t, "", key)
+	assert.Equal(t, headersSizeInBytes+8+len("value"), size)
+	assert.Equal(t, ErrKeysNotStored, cache.Save(&bytes.Buffer{}))
+	assert.Equal(t, ErrKeysNotStored, cache.Rehash(newDefaultHasher()))
+}
+
+func TestHashOnlyKeyStorage(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hashStub(5),
+		KeyStorage:         HashOnly,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	cachedValue, err := cache.Get("other")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+}
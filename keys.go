@@ -0,0 +1,39 @@
+package bigcache
+
+import "strings"
+
+// Keys returns keys of all entries in no particular order, including expired entries which were not evicted yet.
+// Only keys are read, values are neither decoded nor copied. Every shard is read under its read lock,
+// so the result is not a consistent snapshot of the whole cache. Keys are not returned when
+// Config.KeyStorage does not keep them.
+func (c *BigCache) Keys() []string {
+	return c.keysWithPrefix("")
+}
+
+// KeysByPrefix returns keys starting with prefix like Keys. Prefix is normalized like keys,
+// e.g. lower cased with Config.CaseInsensitiveKeys. Keys are copied only when they match.
+func (c *BigCache) KeysByPrefix(prefix string) []string {
+	return c.keysWithPrefix(c.normalizeKey(prefix))
+}
+
+func (c *BigCache) keysWithPrefix(prefix string) []string {
+	if c.config.KeyStorage != FullKey {
+		return nil
+	}
+	var keys []string
+	for _, shard := range c.shards {
+		shard.lock.RLock()
+		for _, index := range shard.hashmap {
+			wrappedEntry, err := shard.entries.Get(int(index))
+			if err != nil {
+				continue
+			}
+			key := readStoredKeyBytesFromEntry(wrappedEntry)
+			if strings.HasPrefix(bytesToString(key), prefix) {
+				keys = append(keys, string(key))
+			}
+		}
+		shard.lock.RUnlock()
+	}
+	return keys
+}
@@ -0,0 +1,53 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:              4,
+		LifeWindow:          5 * time.Second,
+		MaxEntriesInWindow:  10,
+		MaxEntrySize:        256,
+		CaseInsensitiveKeys: true,
+	})
+	cache.Set("user:1", []byte("value"))
+	cache.Set("user:2", []byte("value"))
+	cache.Set("session:1", []byte("value"))
+	cache.Delete("user:2")
+
+	// when
+	keys := cache.Keys()
+	userKeys := cache.KeysByPrefix("USER:")
+
+	// then
+	assert.ElementsMatch(t, []string{"user:1", "session:1"}, keys)
+	assert.Equal(t, []string{"user:1"}, userKeys)
+}
+
+func TestKeysWithHashOnlyKeyStorage(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		KeyStorage:         HashOnly,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	keys := cache.Keys()
+
+	// then
+	assert.Empty(t, keys)
+}
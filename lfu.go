@@ -0,0 +1,96 @@
+package bigcache
+
+const (
+	sketchDepth    = 4  // Number of counter rows in frequency sketch
+	sketchMaxCount = 15 // Counters saturate at this value, like 4 bit counters of TinyLFU
+	// Counters are halved after this many increments per counter column, so old popularity fades out
+	sketchResetMultiplier = 10
+)
+
+// Seeds mixing hashed key into a different column of every sketch row
+var sketchSeeds = [sketchDepth]uint64{0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325}
+
+// frequencySketch is a count-min sketch estimating how often hashed keys were accessed recently.
+// It takes a few bytes per expected entry no matter how many distinct keys are seen.
+// It is not safe for concurrent use, shard lock guards it.
+type frequencySketch struct {
+	counters  [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+func newFrequencySketch(expectedEntries int) *frequencySketch {
+	width := 16
+	for width < expectedEntries {
+		width *= 2
+	}
+	sketch := &frequencySketch{
+		mask:    uint64(width - 1),
+		resetAt: width * sketchResetMultiplier,
+	}
+	for i := range sketch.counters {
+		sketch.counters[i] = make([]uint8, width)
+	}
+	return sketch
+}
+
+func (s *frequencySketch) increment(hashedKey uint64) {
+	for i := range s.counters {
+		counter := &s.counters[i][s.column(hashedKey, i)]
+		if *counter < sketchMaxCount {
+			*counter++
+		}
+	}
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.halve()
+	}
+}
+
+func (s *frequencySketch) estimate(hashedKey uint64) uint8 {
+	estimate := uint8(sketchMaxCount)
+	for i := range s.counters {
+		if counter := s.counters[i][s.column(hashedKey, i)]; counter < estimate {
+			estimate = counter
+		}
+	}
+	return estimate
+}
+
+func (s *frequencySketch) halve() {
+	for i := range s.counters {
+		for j := range s.counters[i] {
+			s.counters[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+}
+
+func (s *frequencySketch) column(hashedKey uint64, row int) uint64 {
+	mixed := (hashedKey ^ hashedKey>>32) * sketchSeeds[row]
+	return (mixed >> 32) & s.mask
+}
+
+// admit decides whether a new entry of given size may evict entries of the shard under TinyLFU policy.
+// Entry is admitted when the shard has room for it, or when its key was accessed more often recently
+// than the key of the oldest live entry which would be evicted first.
+func (c *BigCache) admit(shard *cacheShard, hashedKey uint64, entrySize int) bool {
+	full := c.config.MaxEntriesPerShard > 0 && len(shard.hashmap) >= c.config.MaxEntriesPerShard
+	if c.maxShardSize > 0 && shard.entries.Used()+entrySize > c.maxShardSize {
+		full = true
+	}
+	if !full {
+		return true
+	}
+
+	var victim []byte
+	shard.entries.Iterate(func(index int, data []byte) bool {
+		if shard.hashmap[readHashFromEntry(data)] == uint64(index) {
+			victim = data
+			return false
+		}
+		return true
+	})
+	return victim == nil || shard.sketch.estimate(hashedKey) > shard.sketch.estimate(homeHash(victim))
+}
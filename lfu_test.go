@@ -0,0 +1,63 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrequencySketch(t *testing.T) {
+	t.Parallel()
+
+	// given
+	sketch := newFrequencySketch(16)
+
+	// when
+	for i := 0; i < 5; i++ {
+		sketch.increment(1)
+	}
+	sketch.increment(2)
+
+	// then
+	assert.Equal(t, uint8(5), sketch.estimate(1))
+	assert.Equal(t, uint8(1), sketch.estimate(2))
+	assert.Equal(t, uint8(0), sketch.estimate(3))
+	sketch.halve()
+	assert.Equal(t, uint8(2), sketch.estimate(1))
+}
+
+func TestTinyLFUAdmitsOnlyFrequentKeys(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 2,
+		EvictionPolicy:     TinyLFU,
+	})
+	cache.Set("hot", []byte("value"))
+	cache.Set("warm", []byte("value"))
+	for i := 0; i < 3; i++ {
+		cache.Get("hot")
+		cache.Get("warm")
+	}
+
+	// when
+	rejectedErr := cache.Set("cold", []byte("value"))
+	for i := 0; i < 5; i++ {
+		cache.Get("rising")
+	}
+	admittedErr := cache.Set("rising", []byte("value"))
+
+	// then
+	assert.Equal(t, ErrEntryNotAdmitted, rejectedErr)
+	assert.NoError(t, admittedErr)
+	assert.False(t, cache.Has("cold"))
+	assert.False(t, cache.Has("hot"))
+	assert.True(t, cache.Has("warm"))
+	assert.True(t, cache.Has("rising"))
+}
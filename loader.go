@@ -0,0 +1,109 @@
+package bigcache
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// loadCall is a loader call in progress, waited for by all concurrent GetOrLoad calls for the same key
+type loadCall struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// loadGroup deduplicates concurrent loads of the same key
+type loadGroup struct {
+	lock  sync.Mutex
+	calls map[string]*loadCall
+}
+
+// GetOrLoad reads copy of the entry for the key, calling loader when the key is missing or expired and storing
+// loaded value with returned ttl like SetWithTTL. Concurrent calls for the same key which miss the cache wait for
+// a single loader call and share its result, so a popular key expiring does not hit the backing store many times.
+// Loader errors are returned and nothing is stored. Keys cached with SetNegative return ErrNegativeCached without
+// calling loader. Loaded value is returned even when it cannot be stored, e.g. under TinyLFU admission.
+// Returned value is shared with concurrent callers, so it must not be modified.
+func (c *BigCache) GetOrLoad(key string, loader func(key string) ([]byte, time.Duration, error)) ([]byte, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	value, err := c.getNormalized(key, true)
+	if err == nil || !needsLoad(err) {
+		return value, err
+	}
+
+	call, started := c.loads.start(key)
+	if started {
+		c.load(key, call, loader)
+	} else {
+		<-call.done
+	}
+	return call.value, call.err
+}
+
+// refreshAhead starts loading the key with Config.Loader on a background goroutine unless it is being loaded already.
+// It is called under the shard lock, so the loader must run on another goroutine.
+func (c *BigCache) refreshAhead(key string) {
+	// key outlives the read and may share memory with caller's bytes, see GetBytesKey
+	key = strings.Clone(key)
+	if call, started := c.loads.start(key); started {
+		go c.load(key, call, c.config.Loader)
+	}
+}
+
+// expiresAhead checks whether the entry has less than Config.RefreshAhead of its life window left
+func (c *BigCache) expiresAhead(wrappedEntry []byte) bool {
+	window := c.entryLifeWindow(wrappedEntry)
+	age := entryAge(uint64(c.clock.Epoch()), readTimestampFromEntry(wrappedEntry))
+	return age >= window || float64(window-age) < c.config.RefreshAhead*float64(window)
+}
+
+// start registers load of the key and returns true, or returns the load in progress and false
+func (g *loadGroup) start(key string) (*loadCall, bool) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	if call, ok := g.calls[key]; ok {
+		return call, false
+	}
+	call := &loadCall{done: make(chan struct{})}
+	if g.calls == nil {
+		g.calls = make(map[string]*loadCall)
+	}
+	g.calls[key] = call
+	return call, true
+}
+
+// finish unregisters load of the key and wakes up its waiters
+func (g *loadGroup) finish(key string, call *loadCall) {
+	g.lock.Lock()
+	delete(g.calls, key)
+	g.lock.Unlock()
+	close(call.done)
+}
+
+// load calls loader for the normalized key started with loadGroup.start, stores loaded value and keeps the result in call.
+// Key is not normalized again, as normalization does not have to be idempotent.
+func (c *BigCache) load(key string, call *loadCall, loader func(key string) ([]byte, time.Duration, error)) {
+	defer c.loads.finish(key, call)
+
+	value, ttl, err := loader(key)
+	if err != nil {
+		call.err = err
+		return
+	}
+	// loaded value comes from the backing store, so it is not written back to Config.Store
+	if entry, err := c.beforeSet(key, value); err == nil {
+		c.setWithTTL(key, entry, ttl)
+	}
+	call.value = value
+}
+
+// needsLoad checks whether error of a read means that the value should be loaded
+func needsLoad(err error) bool {
+	return errors.Is(err, ErrEntryNotFound) || errors.Is(err, ErrEntryExpired) || errors.Is(err, ErrCorrupted)
+}
@@ -0,0 +1,211 @@
+package bigcache
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrLoad(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	var loads int
+	loader := func(key string) ([]byte, time.Duration, error) {
+		loads++
+		return []byte("loaded " + key), 20 * time.Second, nil
+	}
+
+	// when
+	first, firstErr := cache.GetOrLoad("key", loader)
+	clock.set(10)
+	second, secondErr := cache.GetOrLoad("key", loader)
+
+	// then
+	assert.NoError(t, firstErr)
+	assert.NoError(t, secondErr)
+	assert.Equal(t, []byte("loaded key"), first)
+	assert.Equal(t, []byte("loaded key"), second)
+	assert.Equal(t, 1, loads)
+}
+
+func TestGetOrLoadNormalizesKeyOnce(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		// strips one version suffix per call, so normalizing twice gives another key
+		KeyNormalizer: func(key string) string {
+			if i := strings.LastIndex(key, "@"); i >= 0 {
+				return key[:i]
+			}
+			return key
+		},
+	})
+	var loadedKey string
+	loader := func(key string) ([]byte, time.Duration, error) {
+		loadedKey = key
+		return []byte("loaded"), 0, nil
+	}
+
+	// when
+	loaded, err := cache.GetOrLoad("item@v2@v1", loader)
+	cached, getErr := cache.Get("item@v2@v1")
+	_, otherErr := cache.Get("item@v2")
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, getErr)
+	assert.Equal(t, []byte("loaded"), loaded)
+	assert.Equal(t, []byte("loaded"), cached)
+	assert.Equal(t, "item@v2", loadedKey)
+	assert.ErrorIs(t, otherErr, ErrEntryNotFound)
+}
+
+func TestGetOrLoadCallsLoaderOnceForConcurrentMisses(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	var loads int32
+	release := make(chan struct{})
+	loader := func(key string) ([]byte, time.Duration, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return []byte("value"), 0, nil
+	}
+	var wg sync.WaitGroup
+	values := make([][]byte, 10)
+
+	// when
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			values[i], _ = cache.GetOrLoad("key", loader)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// then
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+	for _, value := range values {
+		assert.Equal(t, []byte("value"), value)
+	}
+}
+
+func TestGetOrLoadDoesNotStoreLoaderErrors(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	loadErr := errors.New("backing store unavailable")
+	cache.SetNegative("negative", time.Minute)
+
+	// when
+	_, err := cache.GetOrLoad("key", func(key string) ([]byte, time.Duration, error) {
+		return nil, 0, loadErr
+	})
+	_, negativeErr := cache.GetOrLoad("negative", func(key string) ([]byte, time.Duration, error) {
+		t.Fatal("loader called for negative entry")
+		return nil, 0, nil
+	})
+
+	// then
+	assert.Equal(t, loadErr, err)
+	assert.False(t, cache.Has("key"))
+	assert.Equal(t, ErrNegativeCached, negativeErr)
+}
+
+func TestRefreshAhead(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	var loads int32
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		RefreshAhead:       0.3,
+		Loader: func(key string) ([]byte, time.Duration, error) {
+			atomic.AddInt32(&loads, 1)
+			return []byte("refreshed"), 0, nil
+		},
+	}, &clock)
+	cache.Set("key", []byte("value"))
+	clock.set(5)
+	early, _ := cache.Get("key")
+	clock.set(8)
+
+	// when
+	late, err := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), early)
+	assert.Equal(t, []byte("value"), late)
+	assert.Eventually(t, func() bool {
+		value, _ := cache.Get("key")
+		return string(value) == "refreshed"
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+}
+
+func TestRefreshAheadCopiesBytesKey(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	loaded := make(chan string, 1)
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		RefreshAhead:       0.5,
+		Loader: func(key string) ([]byte, time.Duration, error) {
+			loaded <- key
+			return []byte("refreshed"), 0, nil
+		},
+	}, &clock)
+	cache.Set("aaaa", []byte("value"))
+	clock.set(8)
+	key := []byte("aaaa")
+
+	// when
+	cache.GetBytesKey(key)
+	copy(key, "zzzz")
+
+	// then
+	assert.Equal(t, "aaaa", <-loaded)
+}
@@ -0,0 +1,19 @@
+package bigcache
+
+// moveToBack pushes copy of the entry to the end of the shard queue and returns the copy, so the entry is evicted
+// after all entries read or set before it. Space of the old copy is reclaimed when it reaches the head of the queue.
+// It must be called under the shard write lock.
+func (c *BigCache) moveToBack(shard *cacheShard, key string, wrappedEntry []byte) ([]byte, error) {
+	if len(shard.entryBuffer) < len(wrappedEntry) {
+		shard.entryBuffer = make([]byte, len(wrappedEntry))
+	}
+	w := shard.entryBuffer[:len(wrappedEntry)]
+	copy(w, wrappedEntry)
+	hashedKey := readHashFromEntry(w)
+	shard.markDead(wrappedEntry)
+
+	if err := c.push(shard, hashedKey, w); err != nil {
+		return nil, notFound(key)
+	}
+	return shard.entries.Get(int(shard.hashmap[hashedKey]))
+}
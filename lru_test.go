@@ -0,0 +1,61 @@
+package bigcache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUKeepsRecentlyReadEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 1,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+		EvictionPolicy:     LRU,
+	})
+	value := make([]byte, 100*1024)
+
+	// when
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), value)
+		_, err := cache.Get("key-0")
+		assert.NoError(t, err)
+	}
+
+	// then
+	assert.True(t, cache.Has("key-0"))
+	assert.False(t, cache.Has("key-1"))
+	assert.True(t, cache.Has("key-19"))
+}
+
+func TestLRUWithMaxEntriesPerShard(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 2,
+		EvictionPolicy:     LRU,
+	})
+	cache.Set("first", []byte("value"))
+	cache.Set("second", []byte("value"))
+
+	// when
+	cache.Get("first")
+	cache.Set("third", []byte("value"))
+
+	// then
+	assert.True(t, cache.Has("first"))
+	assert.False(t, cache.Has("second"))
+	assert.True(t, cache.Has("third"))
+}
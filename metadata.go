@@ -0,0 +1,95 @@
+package bigcache
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// MaxMetadataSize is the maximum number of bytes serialized metadata of a single entry can take
+const MaxMetadataSize = 1024
+
+// SetWithMetadata saves entry under the key together with small metadata, e.g. content type.
+// Metadata is stored next to the entry and counts into its size, so it is limited to MaxMetadataSize bytes.
+func (c *BigCache) SetWithMetadata(key string, entry []byte, metadata map[string]string) error {
+	encoded, err := encodeMetadata(metadata)
+	if err != nil {
+		return err
+	}
+	return c.setWithMetadata(key, entry, encoded, 0)
+}
+
+// GetWithMetadata reads entry for the key together with metadata it was saved with
+func (c *BigCache) GetWithMetadata(key string) ([]byte, map[string]string, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, nil, err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	unlock := c.lockForRead(shard)
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+	stored := copyStoredValue(wrappedEntry)
+	metadata := decodeMetadata(readMetadataFromEntry(wrappedEntry))
+	unlock()
+
+	value, err := c.decompress(stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, metadata, nil
+}
+
+// encodeMetadata serializes metadata as sorted sequence of length prefixed names and values
+func encodeMetadata(metadata map[string]string) ([]byte, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(metadata))
+	size := 0
+	for name, value := range metadata {
+		names = append(names, name)
+		size += 2*keySizeInBytes + len(name) + len(value)
+	}
+	if size > MaxMetadataSize {
+		return nil, ErrMetadataTooLarge
+	}
+	sort.Strings(names)
+
+	encoded := make([]byte, 0, size)
+	for _, name := range names {
+		encoded = appendMetadataString(encoded, name)
+		encoded = appendMetadataString(encoded, metadata[name])
+	}
+	return encoded, nil
+}
+
+func appendMetadataString(data []byte, value string) []byte {
+	var length [keySizeInBytes]byte
+	binary.LittleEndian.PutUint16(length[:], uint16(len(value)))
+	return append(append(data, length[:]...), value...)
+}
+
+func decodeMetadata(data []byte) map[string]string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	metadata := make(map[string]string)
+	for len(data) > 0 {
+		var name, value string
+		name, data = readMetadataString(data)
+		value, data = readMetadataString(data)
+		metadata[name] = value
+	}
+	return metadata
+}
+
+func readMetadataString(data []byte) (string, []byte) {
+	length := int(binary.LittleEndian.Uint16(data))
+	return string(data[keySizeInBytes : keySizeInBytes+length]), data[keySizeInBytes+length:]
+}
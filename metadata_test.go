@@ -0,0 +1,72 @@
+package bigcache
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetAndGetWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	metadata := map[string]string{"content-type": "application/json", "etag": "abc"}
+
+	// when
+	err := cache.SetWithMetadata("key", []byte("{}"), metadata)
+	cachedValue, cachedMetadata, getErr := cache.GetWithMetadata("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.NoError(t, getErr)
+	assert.Equal(t, []byte("{}"), cachedValue)
+	assert.Equal(t, metadata, cachedMetadata)
+}
+
+func TestGetWithMetadataOfEntryWithoutMetadata(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	cachedValue, cachedMetadata, err := cache.GetWithMetadata("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+	assert.Nil(t, cachedMetadata)
+}
+
+func TestRejectTooLargeMetadata(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	err := cache.SetWithMetadata("key", []byte("value"), map[string]string{"big": strings.Repeat("x", MaxMetadataSize)})
+
+	// then
+	assert.Equal(t, ErrMetadataTooLarge, err)
+	assert.False(t, cache.Has("key"))
+}
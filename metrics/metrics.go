@@ -0,0 +1,78 @@
+// Package metrics exports statistics of BigCache to Prometheus.
+//
+//	collector := metrics.NewCollector(cache, "myservice")
+//	prometheus.MustRegister(collector)
+//
+// Every metric has a shard label with index of the shard, so uneven spread of keys among shards is visible.
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/mikaelnousiainen/bigcache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector is a prometheus.Collector reading statistics of every shard of the cache on every scrape
+type Collector struct {
+	cache         *bigcache.BigCache
+	hits          *prometheus.Desc
+	misses        *prometheus.Desc
+	evictions     *prometheus.Desc
+	reallocations *prometheus.Desc
+	collisions    *prometheus.Desc
+	entries       *prometheus.Desc
+	usedBytes     *prometheus.Desc
+	capacityBytes *prometheus.Desc
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+// NewCollector creates collector of statistics of the cache. Metric names start with namespace followed by bigcache,
+// e.g. myservice_bigcache_hits_total, or with bigcache alone when namespace is empty.
+func NewCollector(cache *bigcache.BigCache, namespace string) *Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "bigcache", name), help, []string{"shard"}, nil)
+	}
+	return &Collector{
+		cache:         cache,
+		hits:          desc("hits_total", "Number of reads which found the key."),
+		misses:        desc("misses_total", "Number of reads which did not find the key."),
+		evictions:     desc("evictions_total", "Number of entries removed to make room for new ones or because they expired."),
+		reallocations: desc("reallocations_total", "Number of times the shard queue allocated additional memory."),
+		collisions:    desc("collisions_total", "Number of detected key collisions."),
+		entries:       desc("entries", "Number of live entries."),
+		usedBytes:     desc("used_bytes", "Number of bytes taken by entries, including removed entries not reclaimed yet."),
+		capacityBytes: desc("capacity_bytes", "Number of bytes allocated for the shard queue."),
+	}
+}
+
+// Describe sends descriptions of all metrics
+func (c *Collector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- c.hits
+	descs <- c.misses
+	descs <- c.evictions
+	descs <- c.reallocations
+	descs <- c.collisions
+	descs <- c.entries
+	descs <- c.usedBytes
+	descs <- c.capacityBytes
+}
+
+// Collect sends current values of all metrics of every shard
+func (c *Collector) Collect(metrics chan<- prometheus.Metric) {
+	for i, shard := range c.cache.ShardStats() {
+		shardIndex := strconv.Itoa(i)
+		metric := func(desc *prometheus.Desc, valueType prometheus.ValueType, value float64) {
+			metrics <- prometheus.MustNewConstMetric(desc, valueType, value, shardIndex)
+		}
+		metric(c.hits, prometheus.CounterValue, float64(shard.Hits))
+		metric(c.misses, prometheus.CounterValue, float64(shard.Misses))
+		metric(c.evictions, prometheus.CounterValue, float64(shard.Evictions))
+		metric(c.reallocations, prometheus.CounterValue, float64(shard.Reallocations))
+		metric(c.collisions, prometheus.CounterValue, float64(shard.Collisions))
+		metric(c.entries, prometheus.GaugeValue, float64(shard.Entries))
+		metric(c.usedBytes, prometheus.GaugeValue, float64(shard.UsedBytes))
+		metric(c.capacityBytes, prometheus.GaugeValue, float64(shard.Capacity))
+	}
+}
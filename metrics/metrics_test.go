@@ -0,0 +1,47 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mikaelnousiainen/bigcache"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollector(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := bigcache.NewBigCache(bigcache.Config{
+		Shards:             2,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	cache.Get("key")
+	cache.Get("missing")
+	collector := NewCollector(cache, "test")
+	metrics := make(chan prometheus.Metric, 100)
+
+	// when
+	collector.Collect(metrics)
+	close(metrics)
+
+	// then
+	values := map[*prometheus.Desc]float64{}
+	shards := map[string]bool{}
+	for metric := range metrics {
+		var written dto.Metric
+		assert.NoError(t, metric.Write(&written))
+		values[metric.Desc()] += written.GetCounter().GetValue() + written.GetGauge().GetValue()
+		shards[written.GetLabel()[0].GetValue()] = true
+	}
+	assert.Len(t, values, 8)
+	assert.Equal(t, map[string]bool{"0": true, "1": true}, shards)
+	assert.Equal(t, float64(1), values[collector.hits])
+	assert.Equal(t, float64(1), values[collector.misses])
+	assert.Equal(t, float64(1), values[collector.entries])
+}
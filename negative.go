@@ -0,0 +1,27 @@
+package bigcache
+
+import "time"
+
+// SetNegative stores a tombstone for the key, recording that the key is known to be missing in the backing store,
+// so reads return ErrNegativeCached instead of a miss worth retrying. The tombstone expires after ttl instead of
+// the life window, so missing keys can be cached for shorter than found values. ttl shorter than a second
+// is rounded up to a second. Set of a real value replaces the tombstone, while Replace and Update treat it as missing. Has reports the tombstone as present and iteration sees it
+// as an entry with empty value.
+func (c *BigCache) SetNegative(key string, ttl time.Duration) error {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return err
+	}
+
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	return c.setWithTimestamp(shard, hashedKey, key, nil, storedValue{}, 0, ttlInSeconds(ttl), flagNegative, uint64(c.clock.Epoch()))
+}
+
+func isNegative(wrappedEntry []byte) bool {
+	return readFlagsFromEntry(wrappedEntry)&flagNegative != 0
+}
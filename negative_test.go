@@ -0,0 +1,81 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetNegative(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+
+	// when
+	err := cache.SetNegative("missing", 2*time.Second)
+	_, negativeErr := cache.Get("missing")
+	clock.set(3)
+	_, expiredErr := cache.Get("missing")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, ErrNegativeCached, negativeErr)
+	assert.ErrorIs(t, expiredErr, ErrEntryExpired)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+}
+
+func TestSetReplacesNegativeEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.SetNegative("key", time.Second)
+
+	// when
+	replaced, _ := cache.Replace("key", []byte("replaced"))
+	cache.Set("key", []byte("value"))
+	value, err := cache.Get("key")
+
+	// then
+	assert.False(t, replaced)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestUpdateTreatsNegativeEntryAsMissing(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         10 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.SetNegative("key", time.Second)
+	var found bool
+
+	// when
+	cache.Update("key", func(old []byte, exists bool) ([]byte, bool) {
+		found = exists
+		return []byte("value"), true
+	})
+	value, _ := cache.Get("key")
+
+	// then
+	assert.False(t, found)
+	assert.Equal(t, []byte("value"), value)
+}
@@ -0,0 +1,112 @@
+package bigcache
+
+import "sync/atomic"
+
+// Number of the oldest live entries in a shard considered when picking an entry to evict by priority
+const evictionCandidates = 8
+
+// SetWithPriority saves entry under the key with given priority. Zero priority is what Set uses.
+// Priority multiplies the life window: entry with priority p is kept for (p+1) life windows before it expires.
+// When MaxEntriesPerShard is reached, the entry with the lowest priority among the oldest live entries of the shard
+// is evicted, the oldest one among equal priorities. When the shard runs out of memory, oldest entries with higher
+// priority than another of the oldest live entries are moved to the back of the shard, so entries with lower
+// priority are evicted first. Expiring entries are still evicted from the oldest, so an entry with high priority
+// at the head of the shard delays eviction of entries set after it.
+func (c *BigCache) SetWithPriority(key string, value []byte, priority uint8) error {
+	return c.setWithMetadata(key, value, nil, priority)
+}
+
+// evictForSpace evicts the oldest entry of the shard to free memory for a push. Live entries at the head with higher
+// priority than another one of evictionCandidates oldest live entries, at most evictionCandidates of them,
+// are taken out of the queue first and pushed back at its end after the eviction. Returns false when the shard is empty.
+func (c *BigCache) evictForSpace(shard *cacheShard) bool {
+	var kept [][]byte
+	for len(kept) < evictionCandidates {
+		head, ok := c.higherPriorityHead(shard)
+		if !ok {
+			break
+		}
+		kept = append(kept, copyBytes(head))
+		shard.entries.Pop()
+	}
+	evicted := c.removeOldestEntry(shard, NoSpace)
+	for _, wrappedEntry := range kept {
+		c.pushBack(shard, wrappedEntry)
+	}
+	return evicted
+}
+
+// pushBack pushes live entry taken out of the queue by evictForSpace at the end of the queue,
+// evicting the oldest entries while it does not fit. The entry itself is evicted when the shard gets empty.
+func (c *BigCache) pushBack(shard *cacheShard, wrappedEntry []byte) {
+	for {
+		index, err := shard.entries.Push(wrappedEntry)
+		if err == nil {
+			shard.hashmap[readHashFromEntry(wrappedEntry)] = uint64(index)
+			return
+		}
+		if !c.removeOldestEntry(shard, NoSpace) {
+			atomic.AddInt64(&shard.stats.Evictions, 1)
+			c.recordEvictedAge(shard, wrappedEntry)
+			c.notifyRemoval(wrappedEntry, NoSpace)
+			c.deleteIndex(shard, wrappedEntry)
+			return
+		}
+	}
+}
+
+// higherPriorityHead returns the oldest entry of the shard when it is live and has higher priority
+// than another one of evictionCandidates oldest live entries
+func (c *BigCache) higherPriorityHead(shard *cacheShard) ([]byte, bool) {
+	var head []byte
+	lower, candidates := false, 0
+	shard.entries.Iterate(func(index int, data []byte) bool {
+		if head == nil {
+			if shard.hashmap[readHashFromEntry(data)] != uint64(index) || readPriorityFromEntry(data) == 0 {
+				return false
+			}
+			head = data
+		}
+		if shard.hashmap[readHashFromEntry(data)] != uint64(index) {
+			return true
+		}
+		lower = readPriorityFromEntry(data) < readPriorityFromEntry(head)
+		candidates++
+		return !lower && candidates < evictionCandidates
+	})
+	return head, lower
+}
+
+// evictLowestPriority evicts live entry with the lowest priority among evictionCandidates oldest ones.
+// The oldest entry is popped from the queue, others are only removed from the hashmap and their space
+// is reclaimed once they reach the head of the queue. Returns false when there is no live entry.
+func (c *BigCache) evictLowestPriority(shard *cacheShard) bool {
+	victimIndex, headIndex, candidates := 0, 0, 0
+	var victim []byte
+	shard.entries.Iterate(func(index int, data []byte) bool {
+		if headIndex == 0 {
+			headIndex = index
+		}
+		if shard.hashmap[readHashFromEntry(data)] != uint64(index) {
+			return true
+		}
+		if victim == nil || readPriorityFromEntry(data) < readPriorityFromEntry(victim) {
+			victimIndex, victim = index, data
+		}
+		candidates++
+		return candidates < evictionCandidates
+	})
+
+	if victim == nil {
+		return false
+	}
+	if victimIndex == headIndex {
+		return c.removeOldestEntry(shard, NoSpace)
+	}
+	atomic.AddInt64(&shard.stats.Evictions, 1)
+	c.recordEvictedAge(shard, victim)
+	c.notifyRemoval(victim, NoSpace)
+	c.deleteIndex(shard, victim)
+	shard.markDead(victim)
+	return true
+}
@@ -0,0 +1,89 @@
+package bigcache
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPriorityExtendsLifeWindow(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.SetWithPriority("important", []byte("value"), 2)
+	cache.Set("regular", []byte("value"))
+
+	// when
+	clock.set(10)
+	_, regularErr := cache.Get("regular")
+	importantValue, importantErr := cache.Get("important")
+
+	// then
+	assert.Error(t, regularErr)
+	assert.NoError(t, importantErr)
+	assert.Equal(t, []byte("value"), importantValue)
+}
+
+func TestLowPriorityEntriesAreEvictedFirst(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 3,
+	})
+	cache.SetWithPriority("oldest", []byte("value"), 1)
+	cache.Set("low", []byte("value"))
+	cache.SetWithPriority("newest", []byte("value"), 1)
+
+	// when
+	cache.Set("key", []byte("value"))
+	cache.Set("key2", []byte("value"))
+
+	// then
+	assert.False(t, cache.Has("low"))
+	assert.False(t, cache.Has("key"))
+	assert.True(t, cache.Has("oldest"))
+	assert.True(t, cache.Has("newest"))
+	assert.True(t, cache.Has("key2"))
+	assert.Equal(t, int64(2), cache.Stats().Evictions)
+}
+
+func TestLowPriorityEntriesAreEvictedFirstWhenShardIsFull(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		HardMaxCacheSize:   1,
+	})
+	value := bytes.Repeat([]byte("a"), 100*1024)
+	cache.SetWithPriority("important", value, 1)
+
+	// when
+	for i := 0; i < 20; i++ {
+		cache.Set(fmt.Sprintf("key-%d", i), value)
+	}
+
+	// then
+	assert.True(t, cache.Has("important"))
+	assert.False(t, cache.Has("key-0"))
+	assert.True(t, cache.Has("key-19"))
+	assert.Greater(t, cache.Stats().Evictions, int64(0))
+}
@@ -21,12 +21,29 @@ const (
 type BytesQueue struct {
 	array        []byte
 	capacity     int
+	maxCapacity  int
 	head         int
 	tail         int
 	count        int
 	rightMargin  int
 	headerBuffer []byte
 	verbose      bool
+	onAllocation func(oldCapacity, newCapacity int, duration time.Duration)
+	// Capacity allocated on start, the queue is never reported as worth shrinking below it
+	initialCapacity int
+	// Shrink policy set by EnableShrinking, zero shrinkAfter disables it
+	shrinkBelow        float64
+	shrinkAfter        int
+	lowUsageOperations int
+}
+
+// QueueStats holds positions and sizes describing internal state of BytesQueue
+type QueueStats struct {
+	Head        int
+	Tail        int
+	RightMargin int
+	Count       int
+	Capacity    int
 }
 
 type queueError struct {
@@ -35,27 +52,59 @@ type queueError struct {
 
 // NewBytesQueue initialize new bytes queue.
 // Initial capacity is used in bytes array allocation
+// Max capacity limits size of the bytes array, zero means no limit
 // When verbose flag is set then information about memory allocation are printed
-func NewBytesQueue(initialCapacity int, verbose bool) *BytesQueue {
+func NewBytesQueue(initialCapacity int, maxCapacity int, verbose bool) *BytesQueue {
 	return &BytesQueue{
-		array:        make([]byte, initialCapacity),
-		capacity:     initialCapacity,
-		headerBuffer: make([]byte, headerEntrySize),
-		tail:         leftMarginIndex,
-		head:         leftMarginIndex,
-		rightMargin:  leftMarginIndex,
-		verbose:      verbose,
+		array:           make([]byte, initialCapacity),
+		capacity:        initialCapacity,
+		maxCapacity:     maxCapacity,
+		headerBuffer:    make([]byte, headerEntrySize),
+		tail:            leftMarginIndex,
+		head:            leftMarginIndex,
+		rightMargin:     leftMarginIndex,
+		verbose:         verbose,
+		initialCapacity: initialCapacity,
+	}
+}
+
+// EnableShrinking makes ShouldShrink report that the queue is worth shrinking once it used less than
+// usedRatio of its capacity for the given number of consecutive Push and Pop operations, while its capacity
+// is above the initial one. The queue does not shrink itself, as moving entries changes their indexes,
+// the owner of the indexes is expected to rebuild the queue, e.g. with Reset and Push.
+func (q *BytesQueue) EnableShrinking(usedRatio float64, operations int) {
+	q.shrinkBelow = usedRatio
+	q.shrinkAfter = operations
+	q.lowUsageOperations = 0
+}
+
+// ShouldShrink reports whether the queue stayed underused as configured by EnableShrinking
+func (q *BytesQueue) ShouldShrink() bool {
+	return q.shrinkAfter > 0 && q.lowUsageOperations >= q.shrinkAfter
+}
+
+// trackUsage counts consecutive operations after which the queue used less than the shrink ratio of its capacity
+func (q *BytesQueue) trackUsage() {
+	if q.shrinkAfter == 0 {
+		return
+	}
+	if q.capacity > q.initialCapacity && float64(q.Used()) < q.shrinkBelow*float64(q.capacity) {
+		q.lowUsageOperations++
+	} else {
+		q.lowUsageOperations = 0
 	}
 }
 
 // Push copies entry at the end of queue and moves tail pointer. Allocates more space if needed.
-// Returns index for pushed data
-func (q *BytesQueue) Push(data []byte) int {
+// Returns index for pushed data or error if maximum capacity would be exceeded
+func (q *BytesQueue) Push(data []byte) (int, error) {
 	dataLen := len(data)
 
 	if q.availableSpaceAfterTail() < dataLen+headerEntrySize {
 		if q.availableSpaceBeforeHead() >= dataLen+headerEntrySize {
 			q.tail = leftMarginIndex
+		} else if q.maxCapacity > 0 && q.capacity+dataLen+headerEntrySize > q.maxCapacity {
+			return -1, &queueError{"Full queue. Maximum size limit reached."}
 		} else {
 			q.allocateAdditionalMemory(dataLen)
 		}
@@ -64,16 +113,27 @@ func (q *BytesQueue) Push(data []byte) int {
 	index := q.tail
 
 	q.push(data, dataLen)
+	q.trackUsage()
+
+	return index, nil
+}
 
-	return index
+// OnAllocation registers function called every time queue allocates additional memory.
+// It receives capacity before and after the allocation and time the allocation took.
+func (q *BytesQueue) OnAllocation(callback func(oldCapacity, newCapacity int, duration time.Duration)) {
+	q.onAllocation = callback
 }
 
 func (q *BytesQueue) allocateAdditionalMemory(minimum int) {
 	start := time.Now()
+	oldCapacity := q.capacity
 	if q.capacity < minimum {
 		q.capacity += minimum
 	}
 	q.capacity = q.capacity * 2
+	if q.maxCapacity > 0 && q.capacity > q.maxCapacity {
+		q.capacity = q.maxCapacity
+	}
 	oldArray := q.array
 	q.array = make([]byte, q.capacity)
 
@@ -91,6 +151,9 @@ func (q *BytesQueue) allocateAdditionalMemory(minimum int) {
 	if q.verbose {
 		log.Printf("Allocated new queue in %s; Capacity: %d \n", time.Since(start), q.capacity)
 	}
+	if q.onAllocation != nil {
+		q.onAllocation(oldCapacity, q.capacity, time.Since(start))
+	}
 }
 
 func (q *BytesQueue) push(data []byte, len int) {
@@ -128,6 +191,7 @@ func (q *BytesQueue) Pop() ([]byte, error) {
 		}
 		q.rightMargin = q.tail
 	}
+	q.trackUsage()
 
 	return data, nil
 }
@@ -137,6 +201,14 @@ func (q *BytesQueue) Clear() {
 	q.tail = leftMarginIndex
 	q.rightMargin = leftMarginIndex
 	q.count = 0
+	q.lowUsageOperations = 0
+}
+
+// Reset removes all entries and replaces bytes array with a new one of given capacity, so memory of the old one can be released
+func (q *BytesQueue) Reset(capacity int) {
+	q.array = make([]byte, capacity)
+	q.capacity = capacity
+	q.Clear()
 }
 
 // Peek reads the oldest entry from list without moving head pointer
@@ -155,16 +227,67 @@ func (q *BytesQueue) Get(index int) ([]byte, error) {
 	if index <= 0 {
 		return nil, &queueError{"Index must be grater than zero. Invalid index."}
 	}
+	if !q.IsValidIndex(index) {
+		return nil, &queueError{"Entry exceeds queue bounds. Invalid index."}
+	}
 
 	data, _ := q.peek(index)
 	return data, nil
 }
 
+// IsValidIndex checks whether header and entry stored at index fit into the queue bytes array.
+// It does not guarantee that index points at the beginning of an entry.
+func (q *BytesQueue) IsValidIndex(index int) bool {
+	if index < leftMarginIndex || index+headerEntrySize > len(q.array) {
+		return false
+	}
+	blockSize := int(binary.LittleEndian.Uint32(q.array[index : index+headerEntrySize]))
+	return blockSize <= len(q.array)-index-headerEntrySize
+}
+
+// Iterate calls accept for all entries from the oldest to the newest one, together with their indexes.
+// Iteration stops when accept returns false.
+func (q *BytesQueue) Iterate(accept func(index int, data []byte) bool) {
+	index := q.head
+	for i := 0; i < q.count; i++ {
+		if index == q.rightMargin {
+			index = leftMarginIndex
+		}
+		data, size := q.peek(index)
+		if !accept(index, data) {
+			return
+		}
+		index += headerEntrySize + size
+	}
+}
+
 // Capacity returns number of allocated bytes for queue
 func (q *BytesQueue) Capacity() int {
 	return q.capacity
 }
 
+// Used returns number of bytes occupied by entries kept in queue, including their headers
+func (q *BytesQueue) Used() int {
+	if q.count == 0 {
+		return 0
+	}
+	if q.tail > q.head {
+		return q.tail - q.head
+	}
+	return q.rightMargin - q.head + q.tail - leftMarginIndex
+}
+
+// Stats returns current positions of head, tail and right margin together with entries count and capacity
+func (q *BytesQueue) Stats() QueueStats {
+	return QueueStats{
+		Head:        q.head,
+		Tail:        q.tail,
+		RightMargin: q.rightMargin,
+		Count:       q.count,
+		Capacity:    q.capacity,
+	}
+}
+
 // Len returns number of entries kept in queue
 func (q *BytesQueue) Len() int {
 	return q.count
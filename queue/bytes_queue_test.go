@@ -1,7 +1,9 @@
 package queue
 
 import (
+	"math"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -10,7 +12,7 @@ func TestPushAndPop(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(10, true)
+	queue := NewBytesQueue(10, 0, true)
 	entry := []byte("hello")
 
 	// when
@@ -24,7 +26,7 @@ func TestPeek(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(100, false)
+	queue := NewBytesQueue(100, 0, false)
 	entry := []byte("hello")
 	queue.Push(entry)
 
@@ -40,7 +42,7 @@ func TestReuseAvailableSpace(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(100, false)
+	queue := NewBytesQueue(100, 0, false)
 
 	// when
 	queue.Push(blob('a', 70))
@@ -57,7 +59,7 @@ func TestAllocateAdditionalSpace(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(11, false)
+	queue := NewBytesQueue(11, 0, false)
 
 	// when
 	queue.Push([]byte("hello1"))
@@ -67,11 +69,29 @@ func TestAllocateAdditionalSpace(t *testing.T) {
 	assert.Equal(t, 22, queue.Capacity())
 }
 
+func TestOnAllocationCallback(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(11, 0, false)
+	var capacities []int
+	queue.OnAllocation(func(oldCapacity, newCapacity int, _ time.Duration) {
+		capacities = append(capacities, oldCapacity, newCapacity)
+	})
+
+	// when
+	queue.Push([]byte("hello1"))
+	queue.Push([]byte("hello2"))
+
+	// then
+	assert.Equal(t, []int{11, 22}, capacities)
+}
+
 func TestAllocateAdditionalSpaceForInsufficientFreeFragmentedSpaceWhereHeadIsBeforeTail(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(25, false)
+	queue := NewBytesQueue(25, 0, false)
 
 	// when
 	queue.Push(blob('a', 3)) // header + entry + left margin = 8 bytes
@@ -89,13 +109,13 @@ func TestUnchangedEntriesIndexesAfterAdditionalMemoryAllocationWhereHeadIsBefore
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(25, false)
+	queue := NewBytesQueue(25, 0, false)
 
 	// when
-	queue.Push(blob('a', 3))                // header + entry + left margin = 8 bytes
-	index := queue.Push(blob('b', 6))       // additional 10 bytes
-	queue.Pop()                             // space freed, 7 bytes available at the beginning
-	newestIndex := queue.Push(blob('c', 6)) // 10 bytes needed, 14 available but not in one segment, allocate additional memory
+	queue.Push(blob('a', 3))                   // header + entry + left margin = 8 bytes
+	index, _ := queue.Push(blob('b', 6))       // additional 10 bytes
+	queue.Pop()                                // space freed, 7 bytes available at the beginning
+	newestIndex, _ := queue.Push(blob('c', 6)) // 10 bytes needed, 14 available but not in one segment, allocate additional memory
 
 	// then
 	assert.Equal(t, 50, queue.Capacity())
@@ -107,7 +127,7 @@ func TestAllocateAdditionalSpaceForInsufficientFreeFragmentedSpaceWhereTailIsBef
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(100, false)
+	queue := NewBytesQueue(100, 0, false)
 
 	// when
 	queue.Push(blob('a', 70)) // header + entry + left margin = 75 bytes
@@ -131,14 +151,14 @@ func TestUnchangedEntriesIndexesAfterAdditionalMemoryAllocationWhereTailIsBefore
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(100, false)
+	queue := NewBytesQueue(100, 0, false)
 
 	// when
-	queue.Push(blob('a', 70))                // header + entry + left margin = 75 bytes
-	index := queue.Push(blob('b', 10))       // 75 + 10 + 4 = 89 bytes
-	queue.Pop()                              // space freed at the beginning
-	queue.Push(blob('c', 30))                // 34 bytes used at the beginning, tail pointer is before head pointer
-	newestIndex := queue.Push(blob('d', 40)) // 44 bytes needed but no available in one segment, allocate new memory
+	queue.Push(blob('a', 70))                   // header + entry + left margin = 75 bytes
+	index, _ := queue.Push(blob('b', 10))       // 75 + 10 + 4 = 89 bytes
+	queue.Pop()                                 // space freed at the beginning
+	queue.Push(blob('c', 30))                   // 34 bytes used at the beginning, tail pointer is before head pointer
+	newestIndex, _ := queue.Push(blob('d', 40)) // 44 bytes needed but no available in one segment, allocate new memory
 
 	// then
 	assert.Equal(t, 200, queue.Capacity())
@@ -150,7 +170,7 @@ func TestAllocateAdditionalSpaceForValueBiggerThanInitQueue(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(11, false)
+	queue := NewBytesQueue(11, 0, false)
 
 	// when
 	queue.Push(blob('a', 100))
@@ -164,7 +184,7 @@ func TestAllocateAdditionalSpaceForValueBiggerThanQueue(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(21, false)
+	queue := NewBytesQueue(21, 0, false)
 
 	// when
 	queue.Push(make([]byte, 2))
@@ -178,11 +198,88 @@ func TestAllocateAdditionalSpaceForValueBiggerThanQueue(t *testing.T) {
 	assert.Equal(t, 242, queue.Capacity())
 }
 
+func TestMaxCapacity(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(1, 20, false)
+
+	// when
+	queue.Push(blob('a', 4))
+	queue.Push(blob('b', 4))
+	_, err := queue.Push(blob('c', 4))
+
+	// then
+	assert.EqualError(t, err, "Full queue. Maximum size limit reached.")
+	assert.Equal(t, 20, queue.Capacity())
+	assert.Equal(t, blob('a', 4), pop(queue))
+	assert.Equal(t, blob('b', 4), pop(queue))
+}
+
+func TestUsed(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(100, 0, false)
+
+	// when
+	queue.Push(blob('a', 70))
+	queue.Push(blob('b', 10))
+	queue.Pop()
+	queue.Push(blob('c', 30))
+
+	// then
+	assert.Equal(t, 14+34, queue.Used())
+	queue.Pop()
+	queue.Pop()
+	assert.Equal(t, 0, queue.Used())
+}
+
+func TestIterate(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(100, 0, false)
+	queue.Push(blob('a', 70))
+	bIndex, _ := queue.Push(blob('b', 10))
+	queue.Pop()
+	cIndex, _ := queue.Push(blob('c', 30))
+	var entries [][]byte
+	var indexes []int
+
+	// when
+	queue.Iterate(func(index int, data []byte) bool {
+		indexes = append(indexes, index)
+		entries = append(entries, data)
+		return true
+	})
+
+	// then
+	assert.Equal(t, []int{bIndex, cIndex}, indexes)
+	assert.Equal(t, [][]byte{blob('b', 10), blob('c', 30)}, entries)
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(100, 0, false)
+
+	// when
+	queue.Push(blob('a', 70))
+	queue.Push(blob('b', 10))
+	queue.Pop()
+	queue.Push(blob('c', 30))
+
+	// then
+	assert.Equal(t, QueueStats{Head: 75, Tail: 35, RightMargin: 89, Count: 2, Capacity: 100}, queue.Stats())
+}
+
 func TestPopWholeQueue(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(13, false)
+	queue := NewBytesQueue(13, 0, false)
 
 	// when
 	queue.Push([]byte("a"))
@@ -200,11 +297,11 @@ func TestGetEntryFromIndex(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(20, false)
+	queue := NewBytesQueue(20, 0, false)
 
 	// when
 	queue.Push([]byte("a"))
-	index := queue.Push([]byte("b"))
+	index, _ := queue.Push([]byte("b"))
 	queue.Push([]byte("c"))
 	result, _ := queue.Get(index)
 
@@ -212,11 +309,45 @@ func TestGetEntryFromIndex(t *testing.T) {
 	assert.Equal(t, []byte("b"), result)
 }
 
+func TestIndexesAboveMaxUint32(t *testing.T) {
+	t.Parallel()
+
+	if testing.Short() || math.MaxInt == math.MaxInt32 {
+		t.Skip("Needs 64-bit platform and a queue bigger than 4 GiB")
+	}
+
+	// given
+	queue := NewBytesQueue(math.MaxUint32+1024, 0, false)
+	// start right below the 4 GiB boundary, as if entries in front were already popped,
+	// so only pages around the boundary are touched
+	queue.head, queue.tail, queue.rightMargin = math.MaxUint32-16, math.MaxUint32-16, math.MaxUint32-16
+	var indexes []int
+
+	// when
+	for _, entry := range []string{"below", "across", "above"} {
+		index, err := queue.Push([]byte(entry))
+		assert.NoError(t, err)
+		indexes = append(indexes, index)
+	}
+
+	// then
+	assert.Less(t, indexes[0], math.MaxUint32)
+	assert.Greater(t, indexes[2], math.MaxUint32)
+	for i, entry := range []string{"below", "across", "above"} {
+		read, err := queue.Get(indexes[i])
+		assert.NoError(t, err)
+		assert.Equal(t, []byte(entry), read)
+	}
+	assert.Equal(t, []byte("below"), pop(queue))
+	assert.Equal(t, []byte("across"), pop(queue))
+	assert.Equal(t, []byte("above"), pop(queue))
+}
+
 func TestGetEntryFromInvalidIndex(t *testing.T) {
 	t.Parallel()
 
 	// given
-	queue := NewBytesQueue(13, false)
+	queue := NewBytesQueue(13, 0, false)
 
 	// when
 	result, err := queue.Get(0)
@@ -226,6 +357,63 @@ func TestGetEntryFromInvalidIndex(t *testing.T) {
 	assert.EqualError(t, err, "Index must be grater than zero. Invalid index.")
 }
 
+func TestGetEntryFromIndexOutOfBounds(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(20, 0, false)
+	queue.Push([]byte("a"))
+
+	// when
+	result, err := queue.Get(18)
+
+	// then
+	assert.Empty(t, result)
+	assert.EqualError(t, err, "Entry exceeds queue bounds. Invalid index.")
+	assert.True(t, queue.IsValidIndex(1))
+	assert.False(t, queue.IsValidIndex(18))
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(10, 0, false)
+	queue.Push(make([]byte, 20))
+
+	// when
+	queue.Reset(5)
+
+	// then
+	assert.Equal(t, 5, queue.Capacity())
+	assert.Equal(t, 0, queue.Len())
+	_, err := queue.Peek()
+	assert.EqualError(t, err, "Empty queue")
+}
+
+func TestShouldShrink(t *testing.T) {
+	t.Parallel()
+
+	// given
+	queue := NewBytesQueue(10, 0, false)
+	queue.EnableShrinking(0.25, 3)
+	for i := 0; i < 10; i++ {
+		queue.Push(make([]byte, 10))
+	}
+
+	// when
+	var reported []bool
+	for i := 0; i < 10; i++ {
+		queue.Pop()
+		reported = append(reported, queue.ShouldShrink())
+	}
+	queue.Reset(10)
+
+	// then
+	assert.Equal(t, []bool{false, false, false, false, false, false, false, false, false, true}, reported)
+	assert.False(t, queue.ShouldShrink())
+}
+
 func pop(queue *BytesQueue) []byte {
 	entry, _ := queue.Pop()
 	return entry
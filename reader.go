@@ -0,0 +1,70 @@
+package bigcache
+
+import (
+	"bytes"
+	"io"
+)
+
+type entryReader struct {
+	*bytes.Reader
+	release func()
+}
+
+// Close releases resources held by the reader. It is safe to call it more than once.
+func (r *entryReader) Close() error {
+	if r.release != nil {
+		r.release()
+		r.release = nil
+	}
+	return nil
+}
+
+// GetReader returns reader over a copy of the entry for the key.
+// The copy is taken under the shard read lock which is released before GetReader returns.
+func (c *BigCache) GetReader(key string) (io.ReadCloser, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	unlock := c.lockForRead(shard)
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	stored := copyStoredValue(wrappedEntry)
+	unlock()
+
+	value, err := c.decompress(stored)
+	if err != nil {
+		return nil, err
+	}
+	return &entryReader{Reader: bytes.NewReader(value)}, nil
+}
+
+// GetZeroCopyReader returns reader over the entry for the key without copying it.
+// The shard lock is held until the reader is closed, so writes to all keys in the shard
+// are blocked meanwhile. The reader must always be closed, preferably as soon as possible.
+func (c *BigCache) GetZeroCopyReader(key string) (io.ReadCloser, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	unlock := c.lockForRead(shard)
+
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	value, err := c.readValue(wrappedEntry)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	return &entryReader{Reader: bytes.NewReader(value), release: unlock}, nil
+}
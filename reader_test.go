@@ -0,0 +1,75 @@
+package bigcache
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetReader(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	reader, err := cache.GetReader("key")
+	cache.Set("key", []byte("other"))
+	read, _ := ioutil.ReadAll(reader)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), read)
+	assert.NoError(t, reader.Close())
+}
+
+func TestGetZeroCopyReaderReleasesLockOnClose(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+
+	// when
+	reader, err := cache.GetZeroCopyReader("key")
+	read, _ := ioutil.ReadAll(reader)
+	reader.Close()
+	reader.Close()
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), read)
+	assert.NoError(t, cache.Set("key", []byte("other")))
+}
+
+func TestGetReaderForMissingEntry(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	_, err := cache.GetZeroCopyReader("key")
+
+	// then
+	assert.EqualError(t, err, "Entry \"key\" not found")
+	assert.NoError(t, cache.Set("key", []byte("value")))
+}
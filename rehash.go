@@ -0,0 +1,64 @@
+package bigcache
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"sync/atomic"
+)
+
+// Rehash places all entries again using newHasher, which becomes the hasher of the cache.
+// Values, metadata and timestamps are preserved. All shards are locked for the whole operation and every
+// entry is copied, so it blocks the cache for O(n) time and temporarily needs memory for all entries.
+// It is meant for a maintenance window and must not be called concurrently with other operations,
+// as hashes computed with the old hasher before the shard lock is taken would point to wrong shards.
+// Entries which keys collide under newHasher are chained like on Set, up to Config.MaxProbeLength of them,
+// when the chain is full the oldest entries are evicted regardless of Config.OnChainFull.
+// Keys are needed to compute new hashes, so ErrKeysNotStored is returned unless KeyStorage is FullKey.
+func (c *BigCache) Rehash(newHasher Hasher) error {
+	if newHasher == nil {
+		return errors.New("Hasher must not be nil")
+	}
+	if c.config.KeyStorage != FullKey {
+		return ErrKeysNotStored
+	}
+	for _, shard := range c.shards {
+		shard.lock.Lock()
+		defer shard.lock.Unlock()
+	}
+
+	var entries [][]byte
+	for _, shard := range c.shards {
+		shard.entries.Iterate(func(index int, data []byte) bool {
+			if shard.hashmap[readHashFromEntry(data)] == uint64(index) {
+				entries = append(entries, copyBytes(data))
+			}
+			return true
+		})
+		shard.entries.Clear()
+		shard.deadBytes = 0
+		atomic.AddInt64(&c.count, -int64(len(shard.hashmap)))
+		shard.hashmap = make(map[uint64]uint64, c.shardSize)
+		shard.chains = nil
+		if shard.bloom != nil {
+			shard.bloom.reset()
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return readTimestampFromEntry(entries[i]) < readTimestampFromEntry(entries[j])
+	})
+
+	c.hash = newHasher
+	c.config.Hasher = newHasher
+	for _, wrappedEntry := range entries {
+		key := readKeyFromEntry(wrappedEntry)
+		hashedKey := newHasher.Sum64(key)
+		shard := c.getShard(hashedKey)
+		probe, _, _ := c.findSlot(shard, key, hashedKey, true)
+		slot := probeSlot(hashedKey, probe)
+		binary.LittleEndian.PutUint64(wrappedEntry[hashOffset:], slot)
+		writeFlagsToEntry(wrappedEntry, readFlagsFromEntry(wrappedEntry)&(1<<probeShift-1)|byte(probe)<<probeShift)
+		c.push(shard, slot, wrappedEntry)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// firstShardHasher places all keys in the first of up to four shards
+type firstShardHasher struct{}
+
+func (firstShardHasher) Sum64(key string) uint64 {
+	return newDefaultHasher().Sum64(key) << 2
+}
+
+func TestRehash(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             firstShardHasher{},
+	}, &clock)
+	cache.Set("key", []byte("value"))
+	clock.set(1)
+	cache.SetWithMetadata("key2", []byte("value2"), map[string]string{"type": "text"})
+
+	// when
+	err := cache.Rehash(newDefaultHasher())
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), cache.Size())
+	value, _ := cache.Get("key")
+	assert.Equal(t, []byte("value"), value)
+	value2, metadata, _ := cache.GetWithMetadata("key2")
+	assert.Equal(t, []byte("value2"), value2)
+	assert.Equal(t, map[string]string{"type": "text"}, metadata)
+	info, _ := cache.GetWithInfo("key2")
+	assert.Equal(t, uint64(1), info.Timestamp)
+}
+
+func TestRehashChainsCollidingEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	cache.Set("key2", []byte("value2"))
+
+	// when
+	err := cache.Rehash(hashStub(5))
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), cache.Size())
+	value, _ := cache.Get("key")
+	assert.Equal(t, []byte("value"), value)
+	value2, _ := cache.Get("key2")
+	assert.Equal(t, []byte("value2"), value2)
+	cache.Delete("key")
+	assert.False(t, cache.Has("key"))
+	assert.True(t, cache.Has("key2"))
+}
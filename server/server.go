@@ -0,0 +1,97 @@
+// Package server exposes BigCache as a REST service, e.g. to run it as a sidecar of services written in other languages.
+//
+//	GET    /api/v1/cache/{key}  reads value of the key
+//	PUT    /api/v1/cache/{key}  sets value of the key to the request body
+//	DELETE /api/v1/cache/{key}  deletes the key
+//	GET    /api/v1/stats        returns statistics of the cache as JSON
+//
+// Keys containing slashes must be path escaped.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/mikaelnousiainen/bigcache"
+)
+
+const (
+	cachePath = "/api/v1/cache/"
+	statsPath = "/api/v1/stats"
+
+	// defaultMaxBodySize limits PUT bodies when the cache does not limit entry size
+	defaultMaxBodySize = 32 << 20
+)
+
+// NewHandler returns handler serving the cache. Values are read and written as raw bytes.
+// Missing and expired keys are answered with 404 Not Found, entries too large for the cache with 413 Request Entity Too Large.
+// PUT bodies are read up to BigCache.EntrySizeLimit, or 32 MiB when the cache does not limit entry size,
+// so a value too large for the cache is rejected without reading it whole. Values are limited before compression.
+func NewHandler(cache *bigcache.BigCache) http.Handler {
+	maxBodySize := int64(cache.EntrySizeLimit())
+	if maxBodySize == 0 {
+		maxBodySize = defaultMaxBodySize
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+cachePath+"{key}", func(w http.ResponseWriter, r *http.Request) {
+		value, err := cache.GetWithCopy(r.PathValue("key"))
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(value)
+	})
+	mux.HandleFunc("PUT "+cachePath+"{key}", func(w http.ResponseWriter, r *http.Request) {
+		value, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := cache.Set(r.PathValue("key"), value); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("DELETE "+cachePath+"{key}", func(w http.ResponseWriter, r *http.Request) {
+		if err := cache.Delete(r.PathValue("key")); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("GET "+statsPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cache.FullStats())
+	})
+	return mux
+}
+
+// writeError answers the request with status code matching the error of the cache
+func writeError(w http.ResponseWriter, err error) {
+	http.Error(w, err.Error(), statusOf(err))
+}
+
+func statusOf(err error) int {
+	switch {
+	case errors.Is(err, bigcache.ErrEntryNotFound), errors.Is(err, bigcache.ErrEntryExpired), errors.Is(err, bigcache.ErrNegativeCached):
+		return http.StatusNotFound
+	case errors.Is(err, bigcache.ErrEmptyKey):
+		return http.StatusBadRequest
+	case errors.Is(err, bigcache.ErrEntryTooLarge), errors.Is(err, bigcache.ErrEntryExceedsShardShare):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, bigcache.ErrCacheClosed):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikaelnousiainen/bigcache"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler() http.Handler {
+	cache, _ := bigcache.NewBigCache(bigcache.Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	return NewHandler(cache)
+}
+
+func serve(handler http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return recorder
+}
+
+func TestCacheEndpoints(t *testing.T) {
+	t.Parallel()
+
+	// given
+	handler := newTestHandler()
+
+	// when
+	put := serve(handler, http.MethodPut, "/api/v1/cache/a%2Fkey", "value")
+	get := serve(handler, http.MethodGet, "/api/v1/cache/a%2Fkey", "")
+	deleted := serve(handler, http.MethodDelete, "/api/v1/cache/a%2Fkey", "")
+	missing := serve(handler, http.MethodGet, "/api/v1/cache/a%2Fkey", "")
+	deletedMissing := serve(handler, http.MethodDelete, "/api/v1/cache/a%2Fkey", "")
+
+	// then
+	assert.Equal(t, http.StatusCreated, put.Code)
+	assert.Equal(t, http.StatusOK, get.Code)
+	assert.Equal(t, "value", get.Body.String())
+	assert.Equal(t, http.StatusOK, deleted.Code)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.Equal(t, http.StatusNotFound, deletedMissing.Code)
+}
+
+func TestPutRejectsBodyBiggerThanEntrySizeLimit(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := bigcache.NewBigCache(bigcache.Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntrySizeHard:   1024,
+	})
+	handler := NewHandler(cache)
+
+	// when
+	tooLarge := serve(handler, http.MethodPut, "/api/v1/cache/key", strings.Repeat("a", 2048))
+	fitting := serve(handler, http.MethodPut, "/api/v1/cache/other", strings.Repeat("a", 512))
+
+	// then
+	assert.Equal(t, http.StatusRequestEntityTooLarge, tooLarge.Code)
+	assert.Equal(t, http.StatusCreated, fitting.Code)
+	assert.False(t, cache.Has("key"))
+}
+
+func TestStatsEndpoint(t *testing.T) {
+	t.Parallel()
+
+	// given
+	handler := newTestHandler()
+	serve(handler, http.MethodPut, "/api/v1/cache/key", "value")
+	serve(handler, http.MethodGet, "/api/v1/cache/key", "")
+	serve(handler, http.MethodGet, "/api/v1/cache/missing", "")
+
+	// when
+	response := serve(handler, http.MethodGet, "/api/v1/stats", "")
+
+	// then
+	var stats bigcache.FullStats
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "application/json", response.Header().Get("Content-Type"))
+	assert.NoError(t, json.Unmarshal(response.Body.Bytes(), &stats))
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+}
@@ -0,0 +1,348 @@
+package bigcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+const snapshotFormatVersion = 2 // Version of the format written by BinarySnapshotCodec
+
+var snapshotMagic = []byte("BCSN") // Bytes starting every binary snapshot
+
+// Size of entry fields written by BinarySnapshotCodec between key and metadata
+const snapshotFieldsSize = ttlSizeInBytes + flagsSizeInBytes + prioritySizeInBytes + versionSizeInBytes + metadataSizeInBytes
+
+// SnapshotCodec writes and reads single entries of a snapshot created by Save and restored by Load.
+// DecodeEntry returns io.EOF when there are no more entries.
+type SnapshotCodec interface {
+	EncodeEntry(w io.Writer, entry EntryInfo) error
+	DecodeEntry(r io.Reader) (EntryInfo, error)
+}
+
+// VersionedSnapshotCodec is a SnapshotCodec which writes a header identifying format and version of the snapshot
+// before the first entry. Load rejects snapshots which header cannot be decoded, instead of reading garbage entries.
+type VersionedSnapshotCodec interface {
+	SnapshotCodec
+	EncodeHeader(w io.Writer) error
+	DecodeHeader(r io.Reader) error
+}
+
+// Save writes copies of all entries to w using Config.SnapshotCodec.
+// Shards are copied one by one under their read locks and written after the lock is released.
+// Keys are part of the snapshot, so ErrKeysNotStored is returned unless KeyStorage is FullKey.
+func (c *BigCache) Save(w io.Writer) error {
+	_, err := c.SaveFiltered(w, nil)
+	return err
+}
+
+// SaveFiltered writes copies of entries for which keep returns true to w like Save and returns number of written entries.
+// Nil keep writes all entries. Keep is called after the shard lock is released with copies of values,
+// so it may use the cache. The result can be restored with Load.
+func (c *BigCache) SaveFiltered(w io.Writer, keep func(key string, value []byte) bool) (written int, err error) {
+	if c.config.KeyStorage != FullKey {
+		return 0, ErrKeysNotStored
+	}
+	buffered := bufio.NewWriter(w)
+	if codec, ok := c.config.SnapshotCodec.(VersionedSnapshotCodec); ok {
+		if err := codec.EncodeHeader(buffered); err != nil {
+			return 0, err
+		}
+	}
+	for _, shard := range c.shards {
+		for _, entry := range c.copyEntries(shard, nil) {
+			if keep != nil && !keep(entry.Key, entry.Value) {
+				continue
+			}
+			if err := c.config.SnapshotCodec.EncodeEntry(buffered, entry); err != nil {
+				return written, err
+			}
+			written++
+		}
+	}
+	return written, buffered.Flush()
+}
+
+// SaveToFile writes snapshot of the cache like Save to a temporary file next to path and renames it to path,
+// so an existing snapshot is replaced only when the new one is complete.
+func (c *BigCache) SaveToFile(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := c.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+// LoadFromFile reads snapshot written by SaveToFile like Load
+func (c *BigCache) LoadFromFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.Load(file)
+}
+
+// Load reads entries written by Save and stores them with their original timestamps, ttl, metadata, priority,
+// version and tombstone and warm tier flags. Entries which outlived their life window and eviction grace period
+// meanwhile are skipped.
+func (c *BigCache) Load(r io.Reader) error {
+	if atomic.LoadInt32(&c.closed) == 1 {
+		return ErrCacheClosed
+	}
+	buffered := bufio.NewReader(r)
+	if codec, ok := c.config.SnapshotCodec.(VersionedSnapshotCodec); ok {
+		if err := codec.DecodeHeader(buffered); err != nil {
+			return err
+		}
+	}
+	for {
+		entry, err := c.config.SnapshotCodec.DecodeEntry(buffered)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		ttl, flags := restoredTTL(entry), snapshotFlags(entry)
+		if entryAge(uint64(c.clock.Epoch()), entry.Timestamp) > c.lifeWindowOf(ttl, flags, entry.Priority)+c.grace {
+			continue
+		}
+		if err := c.restore(entry); err != nil {
+			return err
+		}
+	}
+}
+
+func (c *BigCache) restore(entry EntryInfo) error {
+	value := c.compress(entry.Value)
+	hashedKey := c.hash.Sum64(entry.Key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	err := c.setWithTimestamp(shard, hashedKey, entry.Key, entry.Metadata, value, entry.Priority,
+		restoredTTL(entry), snapshotFlags(entry), entry.Timestamp)
+	if err != nil || entry.Version == 0 {
+		return err
+	}
+	if wrappedEntry, err := c.getWrappedEntry(shard, entry.Key, hashedKey); err == nil {
+		writeVersionToEntry(wrappedEntry, entry.Version)
+	}
+	return nil
+}
+
+// restoredTTL returns ttl of the saved entry in seconds kept in the entry header
+func restoredTTL(entry EntryInfo) uint32 {
+	if entry.ExplicitTTL <= 0 {
+		return 0
+	}
+	return ttlInSeconds(entry.ExplicitTTL)
+}
+
+// snapshotFlags returns flags of the saved entry kept in the entry header and in binary snapshots
+func snapshotFlags(entry EntryInfo) byte {
+	var flags byte
+	if entry.Negative {
+		flags |= flagNegative
+	}
+	if entry.Promoted {
+		flags |= flagPromoted
+	}
+	return flags
+}
+
+// BinarySnapshotCodec is the default SnapshotCodec. Snapshot starts with "BCSN" and format version
+// as two bytes, then every entry is written as timestamp, key length and key, followed by ttl in seconds, flags,
+// priority, version, metadata length and metadata, and value length and value, all integers in little endian.
+type BinarySnapshotCodec struct {
+}
+
+// EncodeHeader writes magic bytes and format version
+func (BinarySnapshotCodec) EncodeHeader(w io.Writer) error {
+	header := make([]byte, len(snapshotMagic)+2)
+	copy(header, snapshotMagic)
+	binary.LittleEndian.PutUint16(header[len(snapshotMagic):], snapshotFormatVersion)
+	_, err := w.Write(header)
+	return err
+}
+
+// DecodeHeader reads magic bytes and format version, returning ErrUnsupportedSnapshot when they do not match
+func (BinarySnapshotCodec) DecodeHeader(r io.Reader) error {
+	header := make([]byte, len(snapshotMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return unexpectedEOF(err)
+	}
+	if !bytes.Equal(header[:len(snapshotMagic)], snapshotMagic) ||
+		binary.LittleEndian.Uint16(header[len(snapshotMagic):]) != snapshotFormatVersion {
+		return ErrUnsupportedSnapshot
+	}
+	return nil
+}
+
+// EncodeEntry writes entry in binary format
+func (BinarySnapshotCodec) EncodeEntry(w io.Writer, entry EntryInfo) error {
+	header := make([]byte, timestampSizeInBytes+keySizeInBytes)
+	binary.LittleEndian.PutUint64(header, entry.Timestamp)
+	binary.LittleEndian.PutUint16(header[timestampSizeInBytes:], uint16(len(entry.Key)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, entry.Key); err != nil {
+		return err
+	}
+
+	fields := make([]byte, snapshotFieldsSize)
+	binary.LittleEndian.PutUint32(fields, restoredTTL(entry))
+	fields[ttlSizeInBytes] = snapshotFlags(entry)
+	fields[ttlSizeInBytes+flagsSizeInBytes] = entry.Priority
+	binary.LittleEndian.PutUint32(fields[ttlSizeInBytes+flagsSizeInBytes+prioritySizeInBytes:], entry.Version)
+	binary.LittleEndian.PutUint16(fields[snapshotFieldsSize-metadataSizeInBytes:], uint16(len(entry.Metadata)))
+	if _, err := w.Write(fields); err != nil {
+		return err
+	}
+	if _, err := w.Write(entry.Metadata); err != nil {
+		return err
+	}
+
+	valueLength := make([]byte, 4)
+	binary.LittleEndian.PutUint32(valueLength, uint32(len(entry.Value)))
+	if _, err := w.Write(valueLength); err != nil {
+		return err
+	}
+	_, err := w.Write(entry.Value)
+	return err
+}
+
+// DecodeEntry reads entry in binary format
+func (BinarySnapshotCodec) DecodeEntry(r io.Reader) (EntryInfo, error) {
+	header := make([]byte, timestampSizeInBytes+keySizeInBytes)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return EntryInfo{}, err
+	}
+	key := make([]byte, binary.LittleEndian.Uint16(header[timestampSizeInBytes:]))
+	if _, err := io.ReadFull(r, key); err != nil {
+		return EntryInfo{}, unexpectedEOF(err)
+	}
+
+	fields := make([]byte, snapshotFieldsSize)
+	if _, err := io.ReadFull(r, fields); err != nil {
+		return EntryInfo{}, unexpectedEOF(err)
+	}
+	var metadata []byte
+	if metadataLength := binary.LittleEndian.Uint16(fields[snapshotFieldsSize-metadataSizeInBytes:]); metadataLength > 0 {
+		metadata = make([]byte, metadataLength)
+		if _, err := io.ReadFull(r, metadata); err != nil {
+			return EntryInfo{}, unexpectedEOF(err)
+		}
+	}
+
+	valueLength := make([]byte, 4)
+	if _, err := io.ReadFull(r, valueLength); err != nil {
+		return EntryInfo{}, unexpectedEOF(err)
+	}
+	value := make([]byte, binary.LittleEndian.Uint32(valueLength))
+	if _, err := io.ReadFull(r, value); err != nil {
+		return EntryInfo{}, unexpectedEOF(err)
+	}
+
+	flags := fields[ttlSizeInBytes]
+	return EntryInfo{
+		Key:         string(key),
+		Value:       value,
+		Timestamp:   binary.LittleEndian.Uint64(header),
+		Metadata:    metadata,
+		Priority:    fields[ttlSizeInBytes+flagsSizeInBytes],
+		Version:     binary.LittleEndian.Uint32(fields[ttlSizeInBytes+flagsSizeInBytes+prioritySizeInBytes:]),
+		ExplicitTTL: time.Duration(binary.LittleEndian.Uint32(fields)) * time.Second,
+		Negative:    flags&flagNegative != 0,
+		Promoted:    flags&flagPromoted != 0,
+	}, nil
+}
+
+// JSONSnapshotCodec writes every entry as a separate line with JSON object holding key, base64 encoded value,
+// timestamp and other entry fields when they are set, which is easy to inspect with external tools.
+type JSONSnapshotCodec struct {
+}
+
+type jsonSnapshotEntry struct {
+	Key       string `json:"key"`
+	Value     []byte `json:"value"`
+	Timestamp uint64 `json:"timestamp"`
+	Metadata  []byte `json:"metadata,omitempty"`
+	Priority  uint8  `json:"priority,omitempty"`
+	Version   uint32 `json:"version,omitempty"`
+	// TTL the entry was set with in seconds
+	TTL      uint32 `json:"ttl,omitempty"`
+	Negative bool   `json:"negative,omitempty"`
+	Promoted bool   `json:"promoted,omitempty"`
+}
+
+// EncodeEntry writes entry as a line of JSON
+func (JSONSnapshotCodec) EncodeEntry(w io.Writer, entry EntryInfo) error {
+	return json.NewEncoder(w).Encode(jsonSnapshotEntry{
+		Key:       entry.Key,
+		Value:     entry.Value,
+		Timestamp: entry.Timestamp,
+		Metadata:  entry.Metadata,
+		Priority:  entry.Priority,
+		Version:   entry.Version,
+		TTL:       restoredTTL(entry),
+		Negative:  entry.Negative,
+		Promoted:  entry.Promoted,
+	})
+}
+
+// DecodeEntry reads entry from a line of JSON
+func (JSONSnapshotCodec) DecodeEntry(r io.Reader) (EntryInfo, error) {
+	reader, ok := r.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(r)
+	}
+	line, err := reader.ReadBytes('\n')
+	if err == io.EOF && len(line) == 0 {
+		return EntryInfo{}, io.EOF
+	}
+	if err != nil && err != io.EOF {
+		return EntryInfo{}, err
+	}
+
+	var entry jsonSnapshotEntry
+	if err := json.Unmarshal(line, &entry); err != nil {
+		return EntryInfo{}, err
+	}
+	return EntryInfo{
+		Key:         entry.Key,
+		Value:       entry.Value,
+		Timestamp:   entry.Timestamp,
+		Metadata:    entry.Metadata,
+		Priority:    entry.Priority,
+		Version:     entry.Version,
+		ExplicitTTL: time.Duration(entry.TTL) * time.Second,
+		Negative:    entry.Negative,
+		Promoted:    entry.Promoted,
+	}, nil
+}
+
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,249 @@
+package bigcache
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	t.Parallel()
+
+	for _, codec := range []SnapshotCodec{BinarySnapshotCodec{}, JSONSnapshotCodec{}} {
+		// given
+		clock := mockedClock{value: 7}
+		config := Config{
+			Shards:             4,
+			LifeWindow:         5 * time.Second,
+			MaxEntriesInWindow: 10,
+			MaxEntrySize:       256,
+			SnapshotCodec:      codec,
+		}
+		cache, _ := newBigCache(config, &clock)
+		cache.Set("key", []byte("value"))
+		cache.Set("other", []byte("other value"))
+		var snapshot bytes.Buffer
+
+		// when
+		saveErr := cache.Save(&snapshot)
+		restored, _ := newBigCache(config, &clock)
+		loadErr := restored.Load(&snapshot)
+
+		// then
+		assert.NoError(t, saveErr)
+		assert.NoError(t, loadErr)
+		assert.Equal(t, uint64(2), restored.Size())
+		info, err := restored.GetWithInfo("other")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("other value"), info.Value)
+		assert.Equal(t, uint64(7), info.Timestamp)
+	}
+}
+
+func TestSaveAndLoadKeepEntryFields(t *testing.T) {
+	t.Parallel()
+
+	for _, codec := range []SnapshotCodec{BinarySnapshotCodec{}, JSONSnapshotCodec{}} {
+		// given
+		clock := mockedClock{value: 0}
+		config := Config{
+			Shards:             4,
+			LifeWindow:         time.Minute,
+			MaxEntriesInWindow: 10,
+			MaxEntrySize:       256,
+			SnapshotCodec:      codec,
+		}
+		cache, _ := newBigCache(config, &clock)
+		cache.SetWithTTL("short", []byte("value"), 2*time.Second)
+		cache.SetNegative("missing", 10*time.Second)
+		cache.Set("tagged", []byte("value"))
+		cache.SetWithMetadata("tagged", []byte("value"), map[string]string{"owner": "alice"})
+		cache.SetWithPriority("important", []byte("value"), 2)
+		var snapshot bytes.Buffer
+
+		// when
+		saveErr := cache.Save(&snapshot)
+		clock.set(1)
+		restored, _ := newBigCache(config, &clock)
+		loadErr := restored.Load(&snapshot)
+
+		// then
+		assert.NoError(t, saveErr)
+		assert.NoError(t, loadErr)
+		_, negativeErr := restored.Get("missing")
+		assert.Equal(t, ErrNegativeCached, negativeErr)
+		_, version, _ := restored.GetWithVersion("tagged")
+		assert.Equal(t, uint32(2), version)
+		_, metadata, _ := restored.GetWithMetadata("tagged")
+		assert.Equal(t, map[string]string{"owner": "alice"}, metadata)
+		info, _ := restored.GetWithInfo("important")
+		assert.Equal(t, uint8(2), info.Priority)
+		clock.set(3)
+		_, expiredErr := restored.Get("short")
+		assert.ErrorIs(t, expiredErr, ErrEntryExpired)
+	}
+}
+
+func TestLoadSkipsEntriesPastTheirTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	config := Config{
+		Shards:             4,
+		LifeWindow:         time.Minute,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}
+	cache, _ := newBigCache(config, &clock)
+	cache.SetWithTTL("short", []byte("value"), 2*time.Second)
+	cache.Set("long", []byte("value"))
+	var snapshot bytes.Buffer
+	cache.Save(&snapshot)
+
+	// when
+	clock.set(50)
+	restored, _ := newBigCache(config, &clock)
+	err := restored.Load(&snapshot)
+
+	// then
+	assert.NoError(t, err)
+	assert.False(t, restored.Has("short"))
+	assert.True(t, restored.Has("long"))
+}
+
+func TestSaveFiltered(t *testing.T) {
+	t.Parallel()
+
+	// given
+	config := Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}
+	cache, _ := NewBigCache(config)
+	cache.Set("user:1", []byte("value"))
+	cache.Set("user:2", []byte("value"))
+	cache.Set("session:1", []byte("value"))
+	var snapshot bytes.Buffer
+
+	// when
+	written, err := cache.SaveFiltered(&snapshot, func(key string, value []byte) bool {
+		return strings.HasPrefix(key, "user:")
+	})
+	restored, _ := NewBigCache(config)
+	restored.Load(&snapshot)
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 2, written)
+	assert.True(t, restored.Has("user:1"))
+	assert.True(t, restored.Has("user:2"))
+	assert.False(t, restored.Has("session:1"))
+}
+
+func TestLoadSkipsExpiredEntries(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	config := Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}
+	cache, _ := newBigCache(config, &clock)
+	cache.Set("old", []byte("value"))
+	clock.set(4)
+	cache.Set("new", []byte("value"))
+	path := filepath.Join(t.TempDir(), "snapshot")
+
+	// when
+	saveErr := cache.SaveToFile(path)
+	clock.set(8)
+	restored, _ := newBigCache(config, &clock)
+	loadErr := restored.LoadFromFile(path)
+
+	// then
+	assert.NoError(t, saveErr)
+	assert.NoError(t, loadErr)
+	assert.False(t, restored.Has("old"))
+	assert.True(t, restored.Has("new"))
+}
+
+func TestLoadRejectsUnknownSnapshotVersion(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	var snapshot bytes.Buffer
+	cache.Save(&snapshot)
+	snapshot.Bytes()[len(snapshotMagic)]++
+
+	// when
+	restored, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	err := restored.Load(&snapshot)
+
+	// then
+	assert.Equal(t, ErrUnsupportedSnapshot, err)
+	assert.Equal(t, uint64(0), restored.Size())
+}
+
+func TestJSONSnapshotIsReadable(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		SnapshotCodec:      JSONSnapshotCodec{},
+	})
+	cache.Set("key", []byte("value"))
+	var snapshot bytes.Buffer
+
+	// when
+	cache.Save(&snapshot)
+
+	// then
+	assert.True(t, strings.HasPrefix(snapshot.String(), `{"key":"key","value":"dmFsdWU=","timestamp":`))
+}
+
+func TestLoadTruncatedSnapshot(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	var snapshot bytes.Buffer
+	cache.Save(&snapshot)
+
+	// when
+	err := cache.Load(bytes.NewReader(snapshot.Bytes()[:snapshot.Len()-1]))
+
+	// then
+	assert.Error(t, err)
+}
@@ -0,0 +1,171 @@
+package bigcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// evictedAgeBounds are upper bounds of EvictedAgeHistogram buckets expressed in life windows
+var evictedAgeBounds = [...]float64{0.25, 0.5, 0.75, 1, 1.25, 1.5, 2, 4}
+
+// EvictedAgeHistogram counts evicted entries by their age at the moment of eviction.
+// Bucket i counts entries younger than FullStats.EvictedAgeBounds[i], the last bucket counts all older entries.
+// Entries evicted well before the life window point at memory pressure, long after it at cleanup lag.
+type EvictedAgeHistogram [len(evictedAgeBounds) + 1]int64
+
+// FullStats holds cache statistics together with data needed to interpret them
+type FullStats struct {
+	Stats
+	// EvictedAgeBounds are upper bounds of EvictedAges buckets, derived from the configured life window
+	EvictedAgeBounds []time.Duration `json:"evictedAgeBounds"`
+}
+
+// Stats stores cache statistics
+type Stats struct {
+	// Hits is a number of successfully found keys
+	Hits int64 `json:"hits"`
+	// Misses is a number of not found keys
+	Misses int64 `json:"misses"`
+	// DelHits is a number of successfully deleted keys
+	DelHits int64 `json:"delHits"`
+	// DelMisses is a number of not deleted keys
+	DelMisses int64 `json:"delMisses"`
+	// Collisions is a number of happened key-collisions
+	Collisions int64 `json:"collisions"`
+	// Evictions is a number of entries removed to make room for new ones or because they expired
+	Evictions int64 `json:"evictions"`
+	// Reallocations is a number of times shard queues allocated additional memory
+	Reallocations int64 `json:"reallocations"`
+	// EvictedAges is a histogram of ages of evicted entries, see FullStats for bucket bounds
+	EvictedAges EvictedAgeHistogram `json:"evictedAges"`
+	// CompressedEntries is a number of entries stored compressed, see Config.CompressAbove.
+	// RawBytes and CompressedBytes are their sizes before and after compression, so their ratio tells
+	// how much memory compression saves.
+	CompressedEntries int64 `json:"compressedEntries"`
+	RawBytes          int64 `json:"rawBytes"`
+	CompressedBytes   int64 `json:"compressedBytes"`
+	// InPlaceOverwrites is a number of sets which reused memory of the previous entry of the key, see Config.OverwriteInPlace
+	InPlaceOverwrites int64 `json:"inPlaceOverwrites"`
+	// Compactions is a number of shard compactions, see Compact and Config.CompactDeadRatio
+	Compactions int64 `json:"compactions"`
+	// RejectedSets is a number of sets rejected because the entry was too large, see Config.MaxEntrySizeHard,
+	// or because collision chain of its hash was full, see Config.OnChainFull
+	RejectedSets int64 `json:"rejectedSets"`
+}
+
+// Stats returns cache statistics aggregated from all shards
+func (c *BigCache) Stats() Stats {
+	var stats Stats
+	for _, shard := range c.shards {
+		stats.Hits += atomic.LoadInt64(&shard.stats.Hits)
+		stats.Misses += atomic.LoadInt64(&shard.stats.Misses)
+		stats.DelHits += atomic.LoadInt64(&shard.stats.DelHits)
+		stats.DelMisses += atomic.LoadInt64(&shard.stats.DelMisses)
+		stats.Collisions += atomic.LoadInt64(&shard.stats.Collisions)
+		stats.Evictions += atomic.LoadInt64(&shard.stats.Evictions)
+		stats.Reallocations += atomic.LoadInt64(&shard.stats.Reallocations)
+		stats.CompressedEntries += atomic.LoadInt64(&shard.stats.CompressedEntries)
+		stats.RawBytes += atomic.LoadInt64(&shard.stats.RawBytes)
+		stats.CompressedBytes += atomic.LoadInt64(&shard.stats.CompressedBytes)
+		stats.InPlaceOverwrites += atomic.LoadInt64(&shard.stats.InPlaceOverwrites)
+		stats.Compactions += atomic.LoadInt64(&shard.stats.Compactions)
+		stats.RejectedSets += atomic.LoadInt64(&shard.stats.RejectedSets)
+		for i := range stats.EvictedAges {
+			stats.EvictedAges[i] += atomic.LoadInt64(&shard.stats.EvictedAges[i])
+		}
+	}
+	return stats
+}
+
+// FullStats returns cache statistics together with bounds of the evicted age histogram buckets
+func (c *BigCache) FullStats() FullStats {
+	bounds := make([]time.Duration, len(evictedAgeBounds))
+	for i, bound := range evictedAgeBounds {
+		bounds[i] = time.Duration(bound * float64(c.lifeWindow) * float64(time.Second))
+	}
+	return FullStats{Stats: c.Stats(), EvictedAgeBounds: bounds}
+}
+
+// recordEvictedAge adds age of the entry to the evicted age histogram of the shard
+func (c *BigCache) recordEvictedAge(shard *cacheShard, wrappedEntry []byte) {
+	age := entryAge(uint64(c.clock.Epoch()), readTimestampFromEntry(wrappedEntry))
+	bucket := len(evictedAgeBounds)
+	for i, bound := range evictedAgeBounds {
+		if float64(age) < bound*float64(c.lifeWindow) {
+			bucket = i
+			break
+		}
+	}
+	atomic.AddInt64(&shard.stats.EvictedAges[bucket], 1)
+}
+
+// ResetStats zeroes statistics of all shards without touching cached entries.
+// Counters are reset one by one, so increments happening concurrently may be kept or lost.
+func (c *BigCache) ResetStats() {
+	for _, shard := range c.shards {
+		atomic.StoreInt64(&shard.stats.Hits, 0)
+		atomic.StoreInt64(&shard.stats.Misses, 0)
+		atomic.StoreInt64(&shard.stats.DelHits, 0)
+		atomic.StoreInt64(&shard.stats.DelMisses, 0)
+		atomic.StoreInt64(&shard.stats.Collisions, 0)
+		atomic.StoreInt64(&shard.stats.Evictions, 0)
+		atomic.StoreInt64(&shard.stats.Reallocations, 0)
+		atomic.StoreInt64(&shard.stats.CompressedEntries, 0)
+		atomic.StoreInt64(&shard.stats.RawBytes, 0)
+		atomic.StoreInt64(&shard.stats.CompressedBytes, 0)
+		atomic.StoreInt64(&shard.stats.InPlaceOverwrites, 0)
+		atomic.StoreInt64(&shard.stats.Compactions, 0)
+		atomic.StoreInt64(&shard.stats.RejectedSets, 0)
+		for i := range shard.stats.EvictedAges {
+			atomic.StoreInt64(&shard.stats.EvictedAges[i], 0)
+		}
+	}
+}
+
+// ShardInfo describes fill of a single shard
+type ShardInfo struct {
+	// Entries is a number of live entries in the shard
+	Entries int `json:"entries"`
+	// UsedBytes is a number of bytes taken by entries in the shard queue, including removed entries not reclaimed yet
+	UsedBytes int `json:"usedBytes"`
+	// Capacity is a number of bytes allocated for the shard queue
+	Capacity int `json:"capacity"`
+	// DeadBytes is a number of bytes taken by deleted and overwritten entries not reclaimed yet, see Compact
+	DeadBytes int `json:"deadBytes"`
+	// OldestEntryAge is age of the entry at the head of the shard queue, zero for an empty shard
+	OldestEntryAge time.Duration `json:"oldestEntryAge"`
+	// Collisions is a number of key collisions detected in the shard
+	Collisions int64 `json:"collisions"`
+	// Hits, Misses, Evictions and Reallocations are counters of the shard summed up by Stats
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Evictions     int64 `json:"evictions"`
+	Reallocations int64 `json:"reallocations"`
+}
+
+// ShardStats returns fill of every shard, e.g. to check that keys are spread evenly among shards.
+// Every shard is inspected under its read lock, so the result is not a consistent snapshot of the whole cache.
+func (c *BigCache) ShardStats() []ShardInfo {
+	currentTimestamp := uint64(c.clock.Epoch())
+	infos := make([]ShardInfo, len(c.shards))
+	for i, shard := range c.shards {
+		shard.lock.RLock()
+		infos[i] = ShardInfo{
+			Entries:       len(shard.hashmap),
+			UsedBytes:     shard.entries.Used(),
+			DeadBytes:     shard.deadBytes,
+			Capacity:      shard.entries.Capacity(),
+			Collisions:    atomic.LoadInt64(&shard.stats.Collisions),
+			Hits:          atomic.LoadInt64(&shard.stats.Hits),
+			Misses:        atomic.LoadInt64(&shard.stats.Misses),
+			Evictions:     atomic.LoadInt64(&shard.stats.Evictions),
+			Reallocations: atomic.LoadInt64(&shard.stats.Reallocations),
+		}
+		if oldestEntry, err := shard.entries.Peek(); err == nil && !isFillerBlob(oldestEntry) {
+			age := entryAge(currentTimestamp, readTimestampFromEntry(oldestEntry))
+			infos[i].OldestEntryAge = time.Duration(age) * time.Second
+		}
+		shard.lock.RUnlock()
+	}
+	return infos
+}
@@ -0,0 +1,164 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hashStub(5),
+	})
+	cache.Set("liquid", []byte("value"))
+
+	// when
+	cache.Get("liquid")
+	cache.Get("liquid")
+	cache.Get("costarring")
+	cache.Delete("liquid")
+	cache.Delete("liquid")
+
+	// then
+	stats := cache.Stats()
+	assert.Equal(t, int64(2), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(1), stats.Collisions)
+	assert.Equal(t, int64(1), stats.DelHits)
+	assert.Equal(t, int64(1), stats.DelMisses)
+}
+
+func TestStatsThroughCacheInterface(t *testing.T) {
+	t.Parallel()
+
+	// given
+	bigCache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	var cache Cache = bigCache
+	cache.Set("key", []byte("value"))
+
+	// when
+	cache.Get("key")
+	cache.Get("missing")
+
+	// then
+	stats := cache.Stats()
+	assert.Equal(t, int64(1), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+}
+
+func TestResetStats(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             16,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	cache.Set("key", []byte("value"))
+	cache.Get("key")
+	cache.Get("missing")
+	cache.Delete("missing")
+
+	// when
+	cache.ResetStats()
+
+	// then
+	assert.Equal(t, Stats{}, cache.Stats())
+	cachedValue, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), cachedValue)
+}
+
+func TestEvictedAgeHistogram(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         4 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		MaxEntriesPerShard: 1,
+	}, &clock)
+	cache.Set("early", []byte("value"))
+	clock.set(1)
+	cache.Set("timely", []byte("value"))
+	clock.set(5)
+
+	// when
+	cache.Set("late", []byte("value"))
+	clock.set(30)
+	cache.Set("last", []byte("value"))
+
+	// then
+	stats := cache.FullStats()
+	assert.Equal(t, int64(3), stats.Evictions)
+	assert.Equal(t, EvictedAgeHistogram{0, 1, 0, 0, 1, 0, 0, 0, 1}, stats.EvictedAges)
+	assert.Equal(t, time.Second, stats.EvictedAgeBounds[0])
+	assert.Equal(t, 4*time.Second, stats.EvictedAgeBounds[3])
+}
+
+func TestEvictedAgeBoundsFollowIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Minute,
+		IdleTimeout:        4 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	stats := cache.FullStats()
+
+	// then
+	assert.Equal(t, time.Second, stats.EvictedAgeBounds[0])
+	assert.Equal(t, 4*time.Second, stats.EvictedAgeBounds[3])
+}
+
+func TestShardStats(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             2,
+		LifeWindow:         50 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Hasher:             hashStub(1),
+	}, &clock)
+	cache.Set("liquid", []byte("value"))
+	clock.set(7)
+	cache.Get("costarring")
+
+	// when
+	stats := cache.ShardStats()
+
+	// then
+	assert.Len(t, stats, 2)
+	assert.Equal(t, ShardInfo{Capacity: stats[0].Capacity}, stats[0])
+	assert.Equal(t, 1, stats[1].Entries)
+	assert.Equal(t, headersSizeInBytes+len("liquid")+len("value")+4, stats[1].UsedBytes)
+	assert.Equal(t, 7*time.Second, stats[1].OldestEntryAge)
+	assert.Equal(t, int64(1), stats[1].Collisions)
+	assert.Equal(t, int64(1), stats[1].Misses)
+}
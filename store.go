@@ -0,0 +1,112 @@
+package bigcache
+
+import (
+	"strings"
+	"sync"
+)
+
+const defaultWriteBehindQueueSize = 1024 // Number of writes buffered by write-behind when Config.WriteBehindQueueSize is not set
+
+// Store is a backing store, e.g. Redis or a database, kept in sync with the cache when set as Config.Store.
+// Put receives entries passed to Set, SetWithTTL and SetMulti, Delete receives keys passed to Delete.
+// Keys are normalized and values are transformed by Config.BeforeSet before they reach the store.
+type Store interface {
+	Put(key string, value []byte) error
+	Delete(key string) error
+}
+
+// storeWrite is a write queued by write-behind, value is nil for Delete
+type storeWrite struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+// writeBehind writes to the store on a background goroutine in the order writes were queued
+type writeBehind struct {
+	store   Store
+	onError func(key string, err error)
+	// Guards closing of writes against concurrent queueing
+	lock    sync.RWMutex
+	closed  bool
+	writes  chan storeWrite
+	flushed chan struct{}
+}
+
+func newWriteBehind(store Store, queueSize int, onError func(key string, err error)) *writeBehind {
+	if queueSize <= 0 {
+		queueSize = defaultWriteBehindQueueSize
+	}
+	w := &writeBehind{
+		store:   store,
+		onError: onError,
+		writes:  make(chan storeWrite, queueSize),
+		flushed: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+func (w *writeBehind) run() {
+	defer close(w.flushed)
+	for write := range w.writes {
+		var err error
+		if write.delete {
+			err = w.store.Delete(write.key)
+		} else {
+			err = w.store.Put(write.key, write.value)
+		}
+		if err != nil && w.onError != nil {
+			w.onError(write.key, err)
+		}
+	}
+}
+
+// enqueue queues the write, blocking while the queue is full. Writes queued after close are dropped.
+func (w *writeBehind) enqueue(write storeWrite) {
+	w.lock.RLock()
+	defer w.lock.RUnlock()
+	if w.closed {
+		return
+	}
+	w.writes <- write
+}
+
+// close stops queueing and waits until all queued writes reach the store
+func (w *writeBehind) close() {
+	w.lock.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.writes)
+	}
+	w.lock.Unlock()
+	<-w.flushed
+}
+
+// putToStore writes the entry to Config.Store. In WriteThrough mode it returns error of the store,
+// in WriteBehind mode it queues copy of the entry and returns nil.
+func (c *BigCache) putToStore(key string, entry []byte) error {
+	if c.config.Store == nil {
+		return nil
+	}
+	if c.writeBehind != nil {
+		value := make([]byte, len(entry))
+		copy(value, entry)
+		// key may share memory with caller's bytes, see SetBytesKey
+		c.writeBehind.enqueue(storeWrite{key: strings.Clone(key), value: value})
+		return nil
+	}
+	return c.config.Store.Put(key, entry)
+}
+
+// deleteFromStore deletes the key from Config.Store like putToStore writes entries
+func (c *BigCache) deleteFromStore(key string) error {
+	if c.config.Store == nil {
+		return nil
+	}
+	if c.writeBehind != nil {
+		c.writeBehind.enqueue(storeWrite{key: strings.Clone(key), delete: true})
+		return nil
+	}
+	return c.config.Store.Delete(key)
+}
@@ -0,0 +1,128 @@
+package bigcache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// mapStore is a Store keeping entries in a map and recording every write
+type mapStore struct {
+	lock    sync.Mutex
+	entries map[string][]byte
+	writes  []string
+	err     error
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{entries: map[string][]byte{}}
+}
+
+func (s *mapStore) Put(key string, value []byte) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.entries[key] = value
+	s.writes = append(s.writes, "put "+key)
+	return nil
+}
+
+func (s *mapStore) Delete(key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	delete(s.entries, key)
+	s.writes = append(s.writes, "delete "+key)
+	return nil
+}
+
+func TestWriteThroughStore(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := newMapStore()
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Store:              store,
+	})
+
+	// when
+	cache.Set("key", []byte("value"))
+	cache.SetWithTTL("ttl", []byte("value"), time.Minute)
+	cache.SetMulti(map[string][]byte{"multi": []byte("value")})
+	cache.Delete("ttl")
+	store.err = errors.New("Store unavailable")
+	err := cache.Set("rejected", []byte("value"))
+
+	// then
+	assert.Equal(t, store.err, err)
+	assert.False(t, cache.Has("rejected"))
+	assert.Equal(t, map[string][]byte{"key": []byte("value"), "multi": []byte("value")}, store.entries)
+}
+
+func TestWriteBehindStoreIsFlushedOnClose(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := newMapStore()
+	var failedKeys []string
+	cache, _ := NewBigCache(Config{
+		Shards:               4,
+		LifeWindow:           5 * time.Second,
+		MaxEntriesInWindow:   10,
+		MaxEntrySize:         256,
+		Store:                store,
+		StoreMode:            WriteBehind,
+		WriteBehindQueueSize: 1,
+		OnStoreError: func(key string, err error) {
+			failedKeys = append(failedKeys, key)
+		},
+	})
+	value := []byte("first")
+
+	// when
+	cache.Set("key", value)
+	copy(value, "later")
+	cache.Set("other", []byte("value"))
+	cache.Delete("other")
+	cache.Close()
+
+	// then
+	assert.Equal(t, []string{"put key", "put other", "delete other"}, store.writes)
+	assert.Equal(t, map[string][]byte{"key": []byte("first")}, store.entries)
+	assert.Empty(t, failedKeys)
+}
+
+func TestWriteBehindStoreCopiesBytesKey(t *testing.T) {
+	t.Parallel()
+
+	// given
+	store := newMapStore()
+	cache, _ := NewBigCache(Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		Store:              store,
+		StoreMode:          WriteBehind,
+	})
+	key := []byte("aaaa")
+
+	// when
+	cache.SetBytesKey(key, []byte("value"))
+	copy(key, "zzzz")
+	cache.Close()
+
+	// then
+	assert.Equal(t, map[string][]byte{"aaaa": []byte("value")}, store.entries)
+}
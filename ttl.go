@@ -0,0 +1,50 @@
+package bigcache
+
+import (
+	"math"
+	"time"
+)
+
+// SetWithTTL saves entry under the key, expiring it after ttl instead of the life window.
+// Reads report the entry as expired once ttl and EvictionGrace pass. ttl is kept in whole seconds, rounded up,
+// and zero or negative ttl makes the entry use the life window like Set. Entries are still evicted from the oldest set,
+// so an entry with a short ttl set after long-lived ones keeps its memory until they are evicted,
+// and an entry with a long ttl at the head of a shard delays eviction of entries set after it.
+func (c *BigCache) SetWithTTL(key string, entry []byte, ttl time.Duration) error {
+	key, entry, err := c.prepareSet(key, entry)
+	if err != nil {
+		return err
+	}
+	if err := c.putToStore(key, entry); err != nil {
+		return err
+	}
+	return c.setWithTTL(key, entry, ttl)
+}
+
+// setWithTTL saves prepared entry with ttl, without writing it to Config.Store
+func (c *BigCache) setWithTTL(key string, entry []byte, ttl time.Duration) error {
+	value := c.compress(entry)
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	var seconds uint32
+	if ttl > 0 {
+		seconds = ttlInSeconds(ttl)
+	}
+	return c.setWithTimestamp(shard, hashedKey, key, nil, value, 0, seconds, 0, uint64(c.clock.Epoch()))
+}
+
+// ttlInSeconds converts ttl to whole seconds kept in the entry header, at least one second and at most max uint32
+func ttlInSeconds(ttl time.Duration) uint32 {
+	seconds := math.Ceil(ttl.Seconds())
+	if seconds < 1 {
+		return 1
+	}
+	if seconds > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(seconds)
+}
@@ -0,0 +1,62 @@
+package bigcache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWithTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+
+	// when
+	cache.SetWithTTL("short", []byte("value"), time.Second)
+	cache.SetWithTTL("long", []byte("value"), 20*time.Second)
+	cache.SetWithTTL("default", []byte("value"), 0)
+	clock.set(6)
+
+	// then
+	_, err := cache.Get("short")
+	assert.ErrorIs(t, err, ErrEntryExpired)
+	_, err = cache.Get("default")
+	assert.ErrorIs(t, err, ErrEntryExpired)
+	value, err := cache.Get("long")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestEntryWithTTLIsEvictedAfterItsTTL(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.SetWithTTL("long", []byte("value"), 10*time.Second)
+
+	// when
+	clock.set(5)
+	cache.Set("first", []byte("value"))
+	keptAfterLifeWindow := cache.Has("long")
+	clock.set(11)
+	cache.Set("second", []byte("value"))
+
+	// then
+	assert.True(t, keptAfterLifeWindow)
+	assert.False(t, cache.Has("long"))
+	assert.Equal(t, int64(1), cache.Stats().Evictions)
+}
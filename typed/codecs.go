@@ -0,0 +1,133 @@
+package typed
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// Format selects one of the built-in codecs with NewCodec, e.g. from application configuration
+type Format int
+
+const (
+	// FormatJSON encodes values with encoding/json, readable by other languages and tools
+	FormatJSON Format = iota
+	// FormatGob encodes values with encoding/gob, for values read only by Go programs
+	FormatGob
+	// FormatProtobuf encodes values which implement proto.Message
+	FormatProtobuf
+	// FormatMsgPack encodes values with MessagePack, more compact and faster than JSON
+	FormatMsgPack
+)
+
+var (
+	_ Codec[any]           = JSONCodec[any]{}
+	_ Codec[any]           = GobCodec[any]{}
+	_ Codec[proto.Message] = ProtoCodec[proto.Message]{}
+	_ Codec[any]           = MsgPackCodec[any]{}
+)
+
+// NewCodec returns built-in codec of the format for values of type T.
+// FormatProtobuf requires T to implement proto.Message, e.g. a pointer to a generated message.
+func NewCodec[T any](format Format) (Codec[T], error) {
+	switch format {
+	case FormatJSON:
+		return JSONCodec[T]{}, nil
+	case FormatGob:
+		return GobCodec[T]{}, nil
+	case FormatProtobuf:
+		var zero T
+		if _, ok := any(zero).(proto.Message); !ok {
+			return nil, fmt.Errorf("Protobuf format needs type implementing proto.Message, got %T", zero)
+		}
+		return protoCodec[T]{}, nil
+	case FormatMsgPack:
+		return MsgPackCodec[T]{}, nil
+	}
+	return nil, fmt.Errorf("Unknown format %d", format)
+}
+
+// JSONCodec encodes values with encoding/json
+type JSONCodec[T any] struct{}
+
+// Encode returns JSON encoding of value
+func (JSONCodec[T]) Encode(value T) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+// Decode parses JSON encoded value
+func (JSONCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, err
+}
+
+// GobCodec encodes values with encoding/gob. Every entry carries its own type information,
+// so entries are bigger than with other codecs for small values.
+type GobCodec[T any] struct{}
+
+// Encode returns gob encoding of value
+func (GobCodec[T]) Encode(value T) ([]byte, error) {
+	var buffer bytes.Buffer
+	err := gob.NewEncoder(&buffer).Encode(value)
+	return buffer.Bytes(), err
+}
+
+// Decode parses gob encoded value
+func (GobCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := gob.NewDecoder(bytes.NewReader(data)).Decode(&value)
+	return value, err
+}
+
+// ProtoCodec encodes protocol buffers messages, T is usually a pointer to a generated message type.
+// Decode creates messages of type T, so interface types such as proto.Message cannot be decoded and return an error.
+type ProtoCodec[T proto.Message] struct {
+	protoCodec[T]
+}
+
+// protoCodec is ProtoCodec for types checked to implement proto.Message at runtime, used by NewCodec
+type protoCodec[T any] struct{}
+
+// Encode returns protocol buffers encoding of value
+func (protoCodec[T]) Encode(value T) ([]byte, error) {
+	message, ok := any(value).(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("Cannot encode nil %v message", reflect.TypeFor[T]())
+	}
+	return proto.Marshal(message)
+}
+
+// Decode parses protocol buffers encoded message into a new message of type T
+func (protoCodec[T]) Decode(data []byte) (T, error) {
+	var zero T
+	prototype, ok := any(zero).(proto.Message)
+	if !ok {
+		return zero, fmt.Errorf("Cannot decode into %v, protobuf codec needs a concrete message type", reflect.TypeFor[T]())
+	}
+	message := prototype.ProtoReflect().New().Interface()
+	if err := proto.Unmarshal(data, message); err != nil {
+		return zero, err
+	}
+	return message.(T), nil
+}
+
+// MsgPackCodec encodes values with MessagePack
+type MsgPackCodec[T any] struct{}
+
+// Encode returns MessagePack encoding of value
+func (MsgPackCodec[T]) Encode(value T) ([]byte, error) {
+	return msgpack.Marshal(value)
+}
+
+// Decode parses MessagePack encoded value
+func (MsgPackCodec[T]) Decode(data []byte) (T, error) {
+	var value T
+	err := msgpack.Unmarshal(data, &value)
+	return value, err
+}
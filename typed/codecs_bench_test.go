@@ -0,0 +1,53 @@
+package typed
+
+import (
+	"testing"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var benchmarkUser = user{Name: "Alice", Age: 30, Roles: []string{"admin", "editor", "viewer"}}
+
+func BenchmarkJSONCodec(b *testing.B) {
+	benchmarkCodec(b, JSONCodec[user]{}, benchmarkUser)
+}
+
+func BenchmarkGobCodec(b *testing.B) {
+	benchmarkCodec(b, GobCodec[user]{}, benchmarkUser)
+}
+
+func BenchmarkMsgPackCodec(b *testing.B) {
+	benchmarkCodec(b, MsgPackCodec[user]{}, benchmarkUser)
+}
+
+func BenchmarkProtoCodec(b *testing.B) {
+	value, err := structpb.NewStruct(map[string]interface{}{
+		"Name":  benchmarkUser.Name,
+		"Age":   benchmarkUser.Age,
+		"Roles": []interface{}{"admin", "editor", "viewer"},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkCodec(b, ProtoCodec[*structpb.Struct]{}, value)
+}
+
+func benchmarkCodec[T any](b *testing.B, codec Codec[T], value T) {
+	b.Run("Encode", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			codec.Encode(value)
+		}
+	})
+	b.Run("Decode", func(b *testing.B) {
+		data, err := codec.Encode(value)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ReportMetric(float64(len(data)), "bytes/entry")
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			codec.Decode(data)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package typed
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type user struct {
+	Name  string
+	Age   int
+	Roles []string
+}
+
+func TestCodecs(t *testing.T) {
+	t.Parallel()
+
+	for _, format := range []Format{FormatJSON, FormatGob, FormatMsgPack} {
+		// given
+		codec, err := NewCodec[user](format)
+		assert.NoError(t, err)
+		value := user{Name: "Alice", Age: 30, Roles: []string{"admin"}}
+
+		// when
+		data, encodeErr := codec.Encode(value)
+		decoded, decodeErr := codec.Decode(data)
+
+		// then
+		assert.NoError(t, encodeErr, format)
+		assert.NoError(t, decodeErr, format)
+		assert.Equal(t, value, decoded, format)
+	}
+}
+
+func TestProtoCodec(t *testing.T) {
+	t.Parallel()
+
+	// given
+	selected, err := NewCodec[*wrapperspb.StringValue](FormatProtobuf)
+	assert.NoError(t, err)
+	value := wrapperspb.String("value")
+
+	for _, codec := range []Codec[*wrapperspb.StringValue]{ProtoCodec[*wrapperspb.StringValue]{}, selected} {
+		// when
+		data, encodeErr := codec.Encode(value)
+		decoded, decodeErr := codec.Decode(data)
+
+		// then
+		assert.NoError(t, encodeErr)
+		assert.NoError(t, decodeErr)
+		assert.True(t, proto.Equal(value, decoded))
+	}
+}
+
+func TestProtoCodecWithInterfaceType(t *testing.T) {
+	t.Parallel()
+
+	// given
+	codec := ProtoCodec[proto.Message]{}
+	data, _ := proto.Marshal(wrapperspb.String("value"))
+
+	// when
+	_, nilErr := codec.Encode(nil)
+	_, decodeErr := codec.Decode(data)
+
+	// then
+	assert.EqualError(t, nilErr, "Cannot encode nil protoreflect.ProtoMessage message")
+	assert.EqualError(t, decodeErr, "Cannot decode into protoreflect.ProtoMessage, protobuf codec needs a concrete message type")
+}
+
+func TestNewCodecErrors(t *testing.T) {
+	t.Parallel()
+
+	// when
+	_, protoErr := NewCodec[user](FormatProtobuf)
+	_, unknownErr := NewCodec[user](Format(42))
+
+	// then
+	assert.EqualError(t, protoErr, "Protobuf format needs type implementing proto.Message, got typed.user")
+	assert.EqualError(t, unknownErr, "Unknown format 42")
+}
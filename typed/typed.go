@@ -0,0 +1,114 @@
+// Package typed wraps bigcache.BigCache with values of a single Go type encoded by a Codec,
+// so callers do not convert values to and from bytes around every call.
+//
+//	cache := typed.New[User](bigCache, typed.JSONCodec[User]{})
+//	cache.Set("alice", User{Name: "Alice"})
+//	user, err := cache.Get("alice")
+//
+// Built-in codecs cover JSON, gob, protocol buffers and MessagePack, NewCodec selects one by Format.
+// MessagePack is the fastest and most compact for plain structs, see benchmarks in the package.
+package typed
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mikaelnousiainen/bigcache"
+)
+
+// Codec converts values of type T to bytes stored in the cache and back. It must be safe for concurrent use.
+type Codec[T any] interface {
+	Encode(value T) ([]byte, error)
+	Decode(data []byte) (T, error)
+}
+
+// Cache stores values of type T in the underlying BigCache
+type Cache[T any] struct {
+	cache *bigcache.BigCache
+	codec Codec[T]
+}
+
+// New creates Cache storing values in cache encoded with codec. Entries set through the underlying cache
+// directly must be encoded with the same codec to be readable.
+func New[T any](cache *bigcache.BigCache, codec Codec[T]) *Cache[T] {
+	return &Cache[T]{cache: cache, codec: codec}
+}
+
+// BigCache returns the underlying cache, e.g. to read its stats
+func (c *Cache[T]) BigCache() *bigcache.BigCache {
+	return c.cache
+}
+
+// Get reads and decodes value for the key. Errors of the cache are returned unchanged,
+// so errors.Is works with bigcache.ErrEntryNotFound and others. Value is decoded from a copy,
+// as shard memory may be overwritten by concurrent sets once the shard lock is released.
+func (c *Cache[T]) Get(key string) (T, error) {
+	data, err := c.cache.GetWithCopy(key)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.decode(key, data)
+}
+
+// Set encodes and saves value under the key
+func (c *Cache[T]) Set(key string, value T) error {
+	data, err := c.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return c.cache.Set(key, data)
+}
+
+// SetWithTTL encodes and saves value under the key, expiring it after ttl like BigCache.SetWithTTL
+func (c *Cache[T]) SetWithTTL(key string, value T, ttl time.Duration) error {
+	data, err := c.encode(key, value)
+	if err != nil {
+		return err
+	}
+	return c.cache.SetWithTTL(key, data, ttl)
+}
+
+// GetOrLoad reads value for the key, calling loader and storing the loaded value when it is missing or expired,
+// like BigCache.GetOrLoad
+func (c *Cache[T]) GetOrLoad(key string, loader func(key string) (T, time.Duration, error)) (T, error) {
+	data, err := c.cache.GetOrLoad(key, func(key string) ([]byte, time.Duration, error) {
+		value, ttl, err := loader(key)
+		if err != nil {
+			return nil, 0, err
+		}
+		data, err := c.encode(key, value)
+		return data, ttl, err
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return c.decode(key, data)
+}
+
+// Delete removes entry for the key
+func (c *Cache[T]) Delete(key string) error {
+	return c.cache.Delete(key)
+}
+
+// Has checks whether entry for the key exists
+func (c *Cache[T]) Has(key string) bool {
+	return c.cache.Has(key)
+}
+
+func (c *Cache[T]) encode(key string, value T) ([]byte, error) {
+	data, err := c.codec.Encode(value)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot encode entry %q: %w", key, err)
+	}
+	return data, nil
+}
+
+func (c *Cache[T]) decode(key string, data []byte) (T, error) {
+	value, err := c.codec.Decode(data)
+	if err != nil {
+		return value, fmt.Errorf("Cannot decode entry %q: %w", key, err)
+	}
+	return value, nil
+}
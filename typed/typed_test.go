@@ -0,0 +1,131 @@
+package typed
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mikaelnousiainen/bigcache"
+	"github.com/stretchr/testify/assert"
+)
+
+type intCodec struct{}
+
+func (intCodec) Encode(value int) ([]byte, error) {
+	return []byte(strconv.Itoa(value)), nil
+}
+
+func (intCodec) Decode(data []byte) (int, error) {
+	return strconv.Atoi(string(data))
+}
+
+func newCache(t *testing.T) *bigcache.BigCache {
+	cache, err := bigcache.NewBigCache(bigcache.Config{
+		Shards:             4,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+	assert.NoError(t, err)
+	return cache
+}
+
+func TestSetAndGet(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache := New[int](newCache(t), intCodec{})
+
+	// when
+	setErr := cache.Set("answer", 42)
+	value, getErr := cache.Get("answer")
+	_, missingErr := cache.Get("missing")
+
+	// then
+	assert.NoError(t, setErr)
+	assert.NoError(t, getErr)
+	assert.Equal(t, 42, value)
+	assert.ErrorIs(t, missingErr, bigcache.ErrEntryNotFound)
+}
+
+func TestDecodeError(t *testing.T) {
+	t.Parallel()
+
+	// given
+	bigCache := newCache(t)
+	cache := New[int](bigCache, intCodec{})
+	bigCache.Set("key", []byte("not a number"))
+
+	// when
+	_, err := cache.Get("key")
+
+	// then
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+}
+
+func TestGetOrLoad(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache := New[int](newCache(t), intCodec{})
+	loaderErr := errors.New("Loader failed")
+
+	// when
+	loaded, err := cache.GetOrLoad("key", func(key string) (int, time.Duration, error) {
+		return 7, 0, nil
+	})
+	_, failedErr := cache.GetOrLoad("other", func(key string) (int, time.Duration, error) {
+		return 0, 0, loaderErr
+	})
+	cached, _ := cache.Get("key")
+
+	// then
+	assert.NoError(t, err)
+	assert.Equal(t, 7, loaded)
+	assert.Equal(t, 7, cached)
+	assert.ErrorIs(t, failedErr, loaderErr)
+	assert.False(t, cache.Has("other"))
+}
+
+func TestConcurrentSetAndGet(t *testing.T) {
+	t.Parallel()
+
+	// given
+	bigCache, err := bigcache.NewBigCache(bigcache.Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		OverwriteInPlace:   true,
+	})
+	assert.NoError(t, err)
+	cache := New[int](bigCache, intCodec{})
+	cache.Set("key", 1000)
+	var wg sync.WaitGroup
+
+	// when
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 1000; j < 2000; j++ {
+				cache.Set("key", j)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				_, err := cache.Get("key")
+				assert.NoError(t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// then
+	value, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, 1999, value)
+}
@@ -0,0 +1,101 @@
+package bigcache
+
+import "bytes"
+
+// GetWithVersion reads entry for the key together with its version. Version is 1 when the key is set
+// for the first time and grows by one with every following set of the key, including Update and AppendBounded.
+// Versions are kept in entries, so a deleted or evicted key starts from 1 again.
+func (c *BigCache) GetWithVersion(key string) ([]byte, uint32, error) {
+	key = c.normalizeKey(key)
+	if err := c.checkKey(key); err != nil {
+		return nil, 0, err
+	}
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	unlock := c.lockForRead(shard)
+	wrappedEntry, err := c.lookupEntry(shard, key, hashedKey)
+	if err != nil {
+		unlock()
+		return nil, 0, err
+	}
+	stored, version := copyStoredValue(wrappedEntry), readVersionFromEntry(wrappedEntry)
+	unlock()
+
+	value, err := c.decompress(stored)
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, version, nil
+}
+
+// SetIfVersion saves entry under the key only if the current version of the entry is expectedVersion,
+// which makes it a compare-and-swap on versions read with GetWithVersion. Zero expectedVersion means the key
+// must be missing, expired or cached as missing. Returns whether the entry was saved. Version is checked and entry
+// is saved under the shard write lock, so concurrent writers of the same version are detected instead of
+// overwriting each other.
+func (c *BigCache) SetIfVersion(key string, entry []byte, expectedVersion uint32) (bool, error) {
+	return c.setIf(key, entry, func(wrappedEntry []byte, found bool) (bool, error) {
+		var version uint32
+		if found {
+			version = readVersionFromEntry(wrappedEntry)
+		}
+		return version == expectedVersion, nil
+	})
+}
+
+// SetIfAbsent saves entry under the key only if the key does not exist, is expired or is cached as missing,
+// like SetIfVersion with zero version. Only one of concurrent callers for the same key saves its entry.
+func (c *BigCache) SetIfAbsent(key string, entry []byte) (bool, error) {
+	return c.SetIfVersion(key, entry, 0)
+}
+
+// SetIfEquals saves newEntry under the key only if the current value of the key equals oldEntry,
+// which makes it a compare-and-swap on values. Missing, expired and negative entries never match.
+// Returns whether the entry was saved. Comparing values costs reading the whole value,
+// use GetWithVersion and SetIfVersion to compare versions instead.
+func (c *BigCache) SetIfEquals(key string, oldEntry, newEntry []byte) (bool, error) {
+	return c.setIf(key, newEntry, func(wrappedEntry []byte, found bool) (bool, error) {
+		if !found {
+			return false, nil
+		}
+		value, err := c.readValue(wrappedEntry)
+		if err != nil {
+			return false, err
+		}
+		return bytes.Equal(value, oldEntry), nil
+	})
+}
+
+// setIf saves entry under the key only if matches accepts the current entry, which is nil and not found
+// when the key is missing, expired or negative. matches runs under the shard write lock.
+func (c *BigCache) setIf(key string, entry []byte, matches func(wrappedEntry []byte, found bool) (bool, error)) (bool, error) {
+	key, entry, err := c.prepareSet(key, entry)
+	if err != nil {
+		return false, err
+	}
+	value := c.compress(entry)
+
+	hashedKey := c.hash.Sum64(key)
+	shard := c.getShard(hashedKey)
+	defer c.notifyTransitions()
+	shard.lock.Lock()
+	defer shard.lock.Unlock()
+
+	wrappedEntry, found := c.liveEntry(shard, key, hashedKey)
+	if ok, err := matches(wrappedEntry, found); !ok || err != nil {
+		return false, err
+	}
+	if err := c.set(shard, hashedKey, key, nil, value, 0); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// liveEntry returns entry for the key unless it is missing, expired or negative, shard must be locked
+func (c *BigCache) liveEntry(shard *cacheShard, key string, hashedKey uint64) ([]byte, bool) {
+	wrappedEntry, err := c.getWrappedEntry(shard, key, hashedKey)
+	if err != nil || c.checkExpired(key, wrappedEntry) != nil || isNegative(wrappedEntry) {
+		return nil, false
+	}
+	return wrappedEntry, true
+}
@@ -0,0 +1,102 @@
+package bigcache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetIfVersion(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	})
+
+	// when
+	created, createErr := cache.SetIfVersion("key", []byte("value"), 0)
+	_, version, _ := cache.GetWithVersion("key")
+	updated, updateErr := cache.SetIfVersion("key", []byte("value2"), version)
+	conflict, conflictErr := cache.SetIfVersion("key", []byte("value3"), version)
+	exists, _ := cache.SetIfVersion("key", []byte("value4"), 0)
+
+	// then
+	assert.NoError(t, createErr)
+	assert.NoError(t, updateErr)
+	assert.NoError(t, conflictErr)
+	assert.True(t, created)
+	assert.Equal(t, uint32(1), version)
+	assert.True(t, updated)
+	assert.False(t, conflict)
+	assert.False(t, exists)
+	value, currentVersion, _ := cache.GetWithVersion("key")
+	assert.Equal(t, []byte("value2"), value)
+	assert.Equal(t, uint32(2), currentVersion)
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	t.Parallel()
+
+	// given
+	clock := mockedClock{value: 0}
+	cache, _ := newBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+	}, &clock)
+	cache.Set("expired", []byte("old"))
+	clock.set(6)
+	cache.SetNegative("negative", time.Minute)
+
+	// when
+	created, createErr := cache.SetIfAbsent("key", []byte("value"))
+	overwritten, _ := cache.SetIfAbsent("key", []byte("value2"))
+	expiredCreated, _ := cache.SetIfAbsent("expired", []byte("new"))
+	negativeCreated, _ := cache.SetIfAbsent("negative", []byte("found"))
+
+	// then
+	assert.NoError(t, createErr)
+	assert.True(t, created)
+	assert.False(t, overwritten)
+	assert.True(t, expiredCreated)
+	assert.True(t, negativeCreated)
+	value, _ := cache.Get("key")
+	assert.Equal(t, []byte("value"), value)
+}
+
+func TestSetIfEquals(t *testing.T) {
+	t.Parallel()
+
+	// given
+	cache, _ := NewBigCache(Config{
+		Shards:             1,
+		LifeWindow:         5 * time.Second,
+		MaxEntriesInWindow: 10,
+		MaxEntrySize:       256,
+		CompressAbove:      1,
+	})
+	value := bytes.Repeat([]byte("value"), 20)
+	cache.Set("key", value)
+
+	// when
+	swapped, swapErr := cache.SetIfEquals("key", value, []byte("new"))
+	conflict, _ := cache.SetIfEquals("key", value, []byte("newer"))
+	missing, _ := cache.SetIfEquals("missing", nil, []byte("value"))
+
+	// then
+	assert.NoError(t, swapErr)
+	assert.True(t, swapped)
+	assert.False(t, conflict)
+	assert.False(t, missing)
+	current, version, _ := cache.GetWithVersion("key")
+	assert.Equal(t, []byte("new"), current)
+	assert.Equal(t, uint32(2), version)
+	assert.False(t, cache.Has("missing"))
+}
@@ -0,0 +1,87 @@
+package bigcache
+
+import "math/bits"
+
+const (
+	xxPrime1 uint64 = 11400714785074694791
+	xxPrime2 uint64 = 14029467366897019727
+	xxPrime3 uint64 = 1609587929392839161
+	xxPrime4 uint64 = 9650029242287828579
+	xxPrime5 uint64 = 2870177450012600261
+)
+
+// NewXXHasher returns Hasher computing 64 bit xxHash with zero seed. It reads the key in 8 byte words,
+// so it is faster than FNV-1a for long keys, and it does not allocate.
+func NewXXHasher() Hasher {
+	return xxHash64{}
+}
+
+type xxHash64 struct {
+}
+
+func (xxHash64) Sum64(key string) uint64 {
+	n := len(key)
+	var h uint64
+	i := 0
+	if n >= 32 {
+		v1, v2, v3, v4 := xxPrime1, xxPrime2, uint64(0), uint64(0)
+		v1 += xxPrime2
+		v4 -= xxPrime1
+		for ; i+32 <= n; i += 32 {
+			v1 = xxRound(v1, readUint64(key, i))
+			v2 = xxRound(v2, readUint64(key, i+8))
+			v3 = xxRound(v3, readUint64(key, i+16))
+			v4 = xxRound(v4, readUint64(key, i+24))
+		}
+		h = bits.RotateLeft64(v1, 1) + bits.RotateLeft64(v2, 7) + bits.RotateLeft64(v3, 12) + bits.RotateLeft64(v4, 18)
+		h = xxMergeRound(h, v1)
+		h = xxMergeRound(h, v2)
+		h = xxMergeRound(h, v3)
+		h = xxMergeRound(h, v4)
+	} else {
+		h = xxPrime5
+	}
+
+	h += uint64(n)
+	for ; i+8 <= n; i += 8 {
+		h ^= xxRound(0, readUint64(key, i))
+		h = bits.RotateLeft64(h, 27)*xxPrime1 + xxPrime4
+	}
+	if i+4 <= n {
+		h ^= uint64(readUint32(key, i)) * xxPrime1
+		h = bits.RotateLeft64(h, 23)*xxPrime2 + xxPrime3
+		i += 4
+	}
+	for ; i < n; i++ {
+		h ^= uint64(key[i]) * xxPrime5
+		h = bits.RotateLeft64(h, 11) * xxPrime1
+	}
+
+	h ^= h >> 33
+	h *= xxPrime2
+	h ^= h >> 29
+	h *= xxPrime3
+	h ^= h >> 32
+	return h
+}
+
+func xxRound(acc, input uint64) uint64 {
+	acc += input * xxPrime2
+	acc = bits.RotateLeft64(acc, 31)
+	return acc * xxPrime1
+}
+
+func xxMergeRound(acc, value uint64) uint64 {
+	acc ^= xxRound(0, value)
+	return acc*xxPrime1 + xxPrime4
+}
+
+// readUint64 reads little endian word from the string without converting it to bytes
+func readUint64(s string, i int) uint64 {
+	return uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+		uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+}
+
+func readUint32(s string, i int) uint32 {
+	return uint32(s[i]) | uint32(s[i+1])<<8 | uint32(s[i+2])<<16 | uint32(s[i+3])<<24
+}